@@ -32,7 +32,7 @@ build_binary() {
     print_info "Building binary..."
     print_info "Version: ${VERSION}, Commit: ${GIT_COMMIT}, Date: ${BUILD_DATE}"
     
-    go build -v \
+    go build -v -tags sqlite_fts5 \
         -ldflags "-X main.Version=${VERSION} -X main.BuildDate=${BUILD_DATE} -X main.GitCommit=${GIT_COMMIT}" \
         -o ${BINARY_NAME}
     
@@ -0,0 +1,116 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Filesystem usage of the NAS's mounted volumes
+
+package assistant
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"syscall"
+)
+
+const mountsPath = "/proc/self/mounts"
+
+// diskFilesystems are the filesystem types that hold user data. Pseudo
+// filesystems, tmpfs and container overlays are left out.
+var diskFilesystems = map[string]bool{
+	"ext2": true, "ext3": true, "ext4": true, "xfs": true, "btrfs": true,
+	"zfs": true, "bcachefs": true, "f2fs": true, "jfs": true, "vfat": true,
+	"exfat": true, "ntfs": true, "ntfs3": true, "fuseblk": true,
+	"nfs": true, "nfs4": true, "cifs": true, "smb3": true,
+}
+
+type DiskUsage struct {
+	Mount       string  `json:"mount"`
+	Device      string  `json:"device"`
+	Filesystem  string  `json:"filesystem"`
+	SizeBytes   uint64  `json:"size_bytes"`
+	UsedBytes   uint64  `json:"used_bytes"`
+	FreeBytes   uint64  `json:"free_bytes"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// diskUsage reports each mounted data filesystem once. A device mounted in
+// several places, such as btrfs subvolumes or bind mounts, is reported at
+// its first mount point.
+func diskUsage() ([]DiskUsage, error) {
+	f, err := os.Open(mountsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	usage := []DiskUsage{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		device, mount, fsType := fields[0], unescapeMount(fields[1]), fields[2]
+		if !diskFilesystems[fsType] || seen[device] {
+			continue
+		}
+		seen[device] = true
+
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(mount, &stat); err != nil {
+			continue
+		}
+
+		blockSize := uint64(stat.Bsize)
+		size := stat.Blocks * blockSize
+		free := stat.Bavail * blockSize
+		used := (stat.Blocks - stat.Bfree) * blockSize
+
+		entry := DiskUsage{
+			Mount:      mount,
+			Device:     device,
+			Filesystem: fsType,
+			SizeBytes:  size,
+			UsedBytes:  used,
+			FreeBytes:  free,
+		}
+		// Like df, relative to the space available to users
+		if used+free > 0 {
+			entry.UsedPercent = float64(used) / float64(used+free) * 100
+		}
+		usage = append(usage, entry)
+	}
+
+	return usage, scanner.Err()
+}
+
+// unescapeMount decodes the octal escapes the kernel uses for spaces and
+// other special characters in mount points.
+func unescapeMount(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if n, ok := octal(path[i+1 : i+4]); ok {
+				b.WriteByte(n)
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+func octal(s string) (byte, bool) {
+	var n int
+	for _, c := range s {
+		if c < '0' || c > '7' {
+			return 0, false
+		}
+		n = n*8 + int(c-'0')
+	}
+	return byte(n), n <= 0xff
+}
@@ -0,0 +1,282 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Tools the chat assistant can call to inspect and manage the NAS
+
+package assistant
+
+import (
+	"bluenode-helper/docker"
+	"bluenode-helper/ollama"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLogLines = 50
+	maxLogLines     = 200
+	// maxToolOutput keeps tool results from filling the model's context.
+	maxToolOutput = 8000
+	// stopTimeout matches the default of the stop and restart endpoints.
+	stopTimeout = 10
+)
+
+// Tool is a function exposed to the model. Mutating tools change the system
+// and are only run after the user confirms them.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+	Mutating    bool
+
+	schema   *ollama.Schema
+	run      func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+	describe func(args map[string]interface{}) string
+}
+
+// Toolbox holds the tools available to the assistant.
+type Toolbox struct {
+	client *docker.Client
+	tools  map[string]*Tool
+	order  []string
+}
+
+func NewToolbox(client *docker.Client) *Toolbox {
+	tb := &Toolbox{
+		client: client,
+		tools:  make(map[string]*Tool),
+	}
+
+	containerParam := `{
+		"type": "object",
+		"properties": {
+			"container": {"type": "string", "description": "Container name"}
+		},
+		"required": ["container"]
+	}`
+
+	tb.add(&Tool{
+		Name:        "list_containers",
+		Description: "List Docker containers with their image, state and status.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"all": {"type": "boolean", "description": "Include stopped containers"}
+			}
+		}`),
+		run: tb.listContainers,
+	})
+	tb.add(&Tool{
+		Name:        "container_logs",
+		Description: "Read the most recent log lines of a container.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"container": {"type": "string", "description": "Container name"},
+				"lines": {"type": "integer", "minimum": 1, "maximum": 200, "description": "Number of lines, default 50"}
+			},
+			"required": ["container"]
+		}`),
+		run: tb.containerLogs,
+	})
+	tb.add(&Tool{
+		Name:        "disk_usage",
+		Description: "Show size, used and free space of the NAS's mounted filesystems.",
+		Parameters:  json.RawMessage(`{"type": "object", "properties": {}}`),
+		run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return diskUsage()
+		},
+	})
+	tb.add(&Tool{
+		Name:        "restart_container",
+		Description: "Restart a container. The user must confirm before it runs.",
+		Parameters:  json.RawMessage(containerParam),
+		Mutating:    true,
+		run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return "restarted", tb.client.RestartContainer(ctx, stringArg(args, "container"), stopTimeout)
+		},
+		describe: func(args map[string]interface{}) string {
+			return "Restart container " + stringArg(args, "container")
+		},
+	})
+	tb.add(&Tool{
+		Name:        "start_container",
+		Description: "Start a stopped container. The user must confirm before it runs.",
+		Parameters:  json.RawMessage(containerParam),
+		Mutating:    true,
+		run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return "started", tb.client.StartContainer(ctx, stringArg(args, "container"))
+		},
+		describe: func(args map[string]interface{}) string {
+			return "Start container " + stringArg(args, "container")
+		},
+	})
+	tb.add(&Tool{
+		Name:        "stop_container",
+		Description: "Stop a running container. The user must confirm before it runs.",
+		Parameters:  json.RawMessage(containerParam),
+		Mutating:    true,
+		run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return "stopped", tb.client.StopContainer(ctx, stringArg(args, "container"), stopTimeout)
+		},
+		describe: func(args map[string]interface{}) string {
+			return "Stop container " + stringArg(args, "container")
+		},
+	})
+
+	return tb
+}
+
+func (tb *Toolbox) add(tool *Tool) {
+	schema, err := ollama.ParseSchema(tool.Parameters)
+	if err != nil {
+		panic(fmt.Sprintf("tool %s: %v", tool.Name, err))
+	}
+	tool.schema = schema
+	tb.tools[tool.Name] = tool
+	tb.order = append(tb.order, tool.Name)
+}
+
+// Definitions returns the tools in the form Ollama expects in a chat
+// request.
+func (tb *Toolbox) Definitions() []ollama.Tool {
+	defs := make([]ollama.Tool, 0, len(tb.order))
+	for _, name := range tb.order {
+		tool := tb.tools[name]
+		defs = append(defs, ollama.Tool{
+			Type: "function",
+			Function: ollama.ToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	return defs
+}
+
+func (tb *Toolbox) Lookup(name string) (*Tool, bool) {
+	tool, ok := tb.tools[name]
+	return tool, ok
+}
+
+// Check validates arguments from the model against the tool's schema.
+func (t *Tool) Check(args map[string]interface{}) error {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	if _, violations := t.schema.Validate(data); len(violations) > 0 {
+		return fmt.Errorf("invalid arguments: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// Describe gives a one-line summary of what a call does, shown to the user
+// when confirming it.
+func (t *Tool) Describe(args map[string]interface{}) string {
+	if t.describe != nil {
+		return t.describe(args)
+	}
+	return t.Name
+}
+
+// Run executes the tool and returns its result as text for the model.
+func (t *Tool) Run(ctx context.Context, args map[string]interface{}) (string, error) {
+	if err := t.Check(args); err != nil {
+		return "", err
+	}
+
+	result, err := t.run(ctx, args)
+	if err != nil {
+		return "", err
+	}
+
+	var output string
+	if text, ok := result.(string); ok {
+		output = text
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode result: %w", err)
+		}
+		output = string(data)
+	}
+
+	// Keep the end, which for logs is the most recent part
+	if len(output) > maxToolOutput {
+		output = "[truncated]\n" + output[len(output)-maxToolOutput:]
+	}
+	return output, nil
+}
+
+type containerSummary struct {
+	Name   string `json:"name"`
+	Image  string `json:"image"`
+	State  string `json:"state"`
+	Status string `json:"status"`
+}
+
+func (tb *Toolbox) listContainers(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	all, _ := args["all"].(bool)
+	containers, err := tb.client.ListContainers(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]containerSummary, 0, len(containers))
+	for _, c := range containers {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		summaries = append(summaries, containerSummary{
+			Name:   name,
+			Image:  c.Image,
+			State:  c.State,
+			Status: c.Status,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+
+	return summaries, nil
+}
+
+func (tb *Toolbox) containerLogs(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	name := stringArg(args, "container")
+	lines := defaultLogLines
+	if n, ok := args["lines"].(float64); ok && n >= 1 {
+		lines = int(n)
+	}
+	if lines > maxLogLines {
+		lines = maxLogLines
+	}
+
+	details, err := tb.client.InspectContainer(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	err = tb.client.TailLogs(ctx, details.ID, lines, details.Config.Tty, func(line docker.LogLine) {
+		fmt.Fprintf(&b, "%s %s %s\n", line.Time.UTC().Format(time.RFC3339), line.Stream, line.Text)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if b.Len() == 0 {
+		return "no log output", nil
+	}
+
+	return b.String(), nil
+}
+
+func stringArg(args map[string]interface{}, name string) string {
+	value, _ := args[name].(string)
+	return strings.TrimPrefix(value, "/")
+}
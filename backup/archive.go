@@ -0,0 +1,313 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Compressed tar archive creation and extraction
+
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// WriteArchive writes the contents of srcDir as a gzip-compressed tar to w,
+// preserving permissions, ownership, timestamps and symlinks.
+func WriteArchive(srcDir string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		// Sockets and devices cannot be restored meaningfully
+		if info.Mode()&(os.ModeSocket|os.ModeDevice|os.ModeNamedPipe) != 0 {
+			return nil
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to archive %s: %w", srcDir, err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish tar stream: %w", err)
+	}
+	return gz.Close()
+}
+
+// CreateArchive writes srcDir to a new archive file and returns its size and
+// SHA-256 checksum.
+func CreateArchive(srcDir, archivePath string) (int64, string, error) {
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0750); err != nil {
+		return 0, "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	f, err := os.OpenFile(archivePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	if err := WriteArchive(srcDir, io.MultiWriter(f, hash, counter)); err != nil {
+		f.Close()
+		os.Remove(archivePath)
+		return 0, "", err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(archivePath)
+		return 0, "", fmt.Errorf("failed to close archive: %w", err)
+	}
+
+	return counter.n, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ExtractArchive unpacks a gzip-compressed tar stream into dstDir. Entries
+// that would escape dstDir are rejected, and nothing is written through a
+// symlink, so an archive cannot plant a link and then write through it.
+// Symlinks are restored as they were: their targets resolve inside the
+// container, not here.
+func ExtractArchive(r io.Reader, dstDir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	root, err := filepath.EvalSymlinks(dstDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve destination: %w", err)
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		target := filepath.Join(root, filepath.FromSlash(header.Name))
+		if target == root {
+			continue
+		}
+		if !within(root, target) {
+			return fmt.Errorf("archive entry escapes destination: %s", header.Name)
+		}
+
+		// A symlink extracted earlier must not redirect this entry, and
+		// the parents are checked before MkdirAll could follow one
+		if err := checkParentWithin(root, target); err != nil {
+			return err
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := removeUnlessDir(target); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := prepareTarget(root, target); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY|syscall.O_NOFOLLOW, mode)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := prepareTarget(root, target); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+			os.Lchown(target, header.Uid, header.Gid)
+			continue
+		case tar.TypeLink:
+			linkTarget := filepath.Join(root, filepath.FromSlash(header.Linkname))
+			if !within(root, linkTarget) {
+				return fmt.Errorf("archive link escapes destination: %s", header.Linkname)
+			}
+			resolved, err := filepath.EvalSymlinks(linkTarget)
+			if err != nil {
+				return err
+			}
+			if !within(root, resolved) {
+				return fmt.Errorf("archive link escapes destination: %s", header.Linkname)
+			}
+			if err := prepareTarget(root, target); err != nil {
+				return err
+			}
+			if err := os.Link(resolved, target); err != nil {
+				return err
+			}
+			continue
+		default:
+			continue
+		}
+
+		os.Chown(target, header.Uid, header.Gid)
+		os.Chmod(target, mode)
+		os.Chtimes(target, header.ModTime, header.ModTime)
+	}
+}
+
+// within reports whether path is root or lies below it, without resolving
+// symlinks.
+func within(root, path string) bool {
+	return path == root || strings.HasPrefix(path, root+string(os.PathSeparator))
+}
+
+// checkParentWithin resolves the nearest existing parent of target and
+// checks that it lies inside root. Parents that do not exist yet cannot be
+// symlinks.
+func checkParentWithin(root, target string) error {
+	dir := filepath.Dir(target)
+	for {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		if !within(root, dir) {
+			return fmt.Errorf("archive entry escapes destination: %s", target)
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	parent, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if !within(root, parent) {
+		return fmt.Errorf("archive entry escapes destination: %s", target)
+	}
+	return nil
+}
+
+// prepareTarget creates the parents of target and removes whatever is at
+// target, so the entry is created fresh instead of written through an
+// existing file or link.
+func prepareTarget(root, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := checkParentWithin(root, target); err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("archive entry replaces a directory: %s", target)
+	}
+	return os.Remove(target)
+}
+
+// removeUnlessDir removes a file or symlink at path so a directory can be
+// created there.
+func removeUnlessDir(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// FileChecksum returns the hex SHA-256 of a file.
+func FileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.n += int64(len(p))
+	return len(p), nil
+}
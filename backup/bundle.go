@@ -0,0 +1,809 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Container migration bundles for moving apps between machines
+
+package backup
+
+import (
+	"archive/tar"
+	"bluenode-helper/docker"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// BundleFormatVersion is written to every bundle. Imports refuse bundles
+	// with a newer version than they understand.
+	BundleFormatVersion = 1
+
+	DefaultMigrationDir = "/var/lib/bnhelper/migrated"
+
+	bundleManifest  = "manifest.json"
+	bundleContainer = "container.json"
+	bundleImage     = "image.tar"
+
+	// maxPortSearch bounds the search for a free host port.
+	maxPortSearch = 1000
+)
+
+type BundleManifest struct {
+	FormatVersion int           `json:"format_version"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Container     string        `json:"container"`
+	Image         string        `json:"image"`
+	ImageID       string        `json:"image_id"`
+	Engine        string        `json:"engine,omitempty"`
+	ImageArchive  BundleFile    `json:"image_archive"`
+	Mounts        []BundleMount `json:"mounts"`
+}
+
+type BundleFile struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+type BundleMount struct {
+	Type        string     `json:"type"`
+	Name        string     `json:"name,omitempty"`
+	Source      string     `json:"source"`
+	Destination string     `json:"destination"`
+	Archive     BundleFile `json:"archive"`
+}
+
+type BundleExportOptions struct {
+	Consistency string
+}
+
+type BundleImportOptions struct {
+	// Name of the new container. Defaults to the original name.
+	Name string
+	// PortMap overrides host ports, keyed by container port ("80/tcp") or by
+	// the original host port ("8080").
+	PortMap map[string]string
+	// PathMap replaces host path prefixes of bind mounts.
+	PathMap map[string]string
+	// DataDir receives bind mount data whose original path is taken.
+	DataDir string
+	Start   bool
+}
+
+type BundleImportResult struct {
+	ContainerID string   `json:"container_id"`
+	Name        string   `json:"name"`
+	Image       string   `json:"image"`
+	Remaps      []Remap  `json:"remaps,omitempty"`
+	Warnings    []string `json:"warnings,omitempty"`
+	Started     bool     `json:"started"`
+}
+
+// Remap records a port, path or volume that was changed on import.
+type Remap struct {
+	Kind string `json:"kind"`
+	What string `json:"what"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ExportBundle writes a single tar file holding a container's inspect
+// document, its image and the data of its volumes and directory bind mounts.
+func (m *Manager) ExportBundle(ctx context.Context, containerID, bundlePath string, opts BundleExportOptions) (*BundleManifest, error) {
+	if err := validateConsistency(opts.Consistency); err != nil {
+		return nil, err
+	}
+
+	raw, err := m.client.InspectContainerRaw(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	var container docker.ContainerJSON
+	if err := json.Unmarshal(raw, &container); err != nil {
+		return nil, fmt.Errorf("failed to decode container: %w", err)
+	}
+
+	work, err := os.MkdirTemp(filepath.Dir(bundlePath), ".bundle-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	manifest := &BundleManifest{
+		FormatVersion: BundleFormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Container:     strings.TrimPrefix(container.Name, "/"),
+		Image:         container.Config.Image,
+		ImageID:       container.Image,
+		Engine:        m.client.Engine(ctx),
+	}
+
+	// Saved by ID so the bundle holds exactly the image the container runs
+	manifest.ImageArchive, err = saveImage(ctx, m.client, container.Image, filepath.Join(work, bundleImage))
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := selectMounts(container.Mounts, nil)
+	if len(mounts) > 0 {
+		resume, err := m.quiesce(ctx, &container, opts.Consistency)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, mount := range mounts {
+			name := fmt.Sprintf("volumes/%d-%s.tar.gz", i, mountLabel(mount))
+			size, checksum, err := CreateArchive(mount.Source, filepath.Join(work, name))
+			if err != nil {
+				resume()
+				return nil, err
+			}
+			manifest.Mounts = append(manifest.Mounts, BundleMount{
+				Type:        mount.Type,
+				Name:        mount.Name,
+				Source:      mount.Source,
+				Destination: mount.Destination,
+				Archive:     BundleFile{Path: name, Size: size, Checksum: checksum},
+			})
+		}
+		resume()
+	}
+
+	if err := writeBundle(bundlePath, work, manifest, raw); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Exported container %s to bundle %s", manifest.Container, bundlePath)
+	return manifest, nil
+}
+
+// ImportBundle recreates a container from a bundle. Host ports that are in
+// use and bind mount paths that already hold data are moved aside, and named
+// volumes that already exist are created under a new name; each change is
+// reported in the result.
+func (m *Manager) ImportBundle(ctx context.Context, bundlePath string, opts BundleImportOptions) (*BundleImportResult, error) {
+	work, err := os.MkdirTemp(filepath.Dir(bundlePath), ".bundle-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	manifest, raw, err := readBundle(bundlePath, work)
+	if err != nil {
+		return nil, err
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = manifest.Container
+	}
+	if _, err := m.client.InspectContainer(ctx, name); err == nil {
+		return nil, fmt.Errorf("%w: a container named %s already exists, choose another name", docker.ErrConflict, name)
+	} else if !errors.Is(err, docker.ErrNotFound) {
+		return nil, err
+	}
+
+	result := &BundleImportResult{Name: name, Image: manifest.Image}
+
+	// Volumes created for the bundle are removed again if the import fails
+	var volumes []string
+	imported := false
+	defer func() {
+		if !imported {
+			m.removeVolumes(context.WithoutCancel(ctx), volumes)
+		}
+	}()
+
+	image, err := m.importImage(ctx, manifest, filepath.Join(work, bundleImage))
+	if err != nil {
+		return nil, err
+	}
+
+	var inspect struct {
+		ID         string                 `json:"Id"`
+		Config     map[string]interface{} `json:"Config"`
+		HostConfig map[string]interface{} `json:"HostConfig"`
+	}
+	if err := json.Unmarshal(raw, &inspect); err != nil {
+		return nil, fmt.Errorf("failed to decode bundled container: %w", err)
+	}
+	config := inspect.Config
+	hostConfig := inspect.HostConfig
+	if config == nil || hostConfig == nil {
+		return nil, fmt.Errorf("bundled container has no configuration")
+	}
+
+	// A hostname equal to the old container ID would be misleading
+	if hostname, _ := config["Hostname"].(string); hostname != "" && strings.HasPrefix(inspect.ID, hostname) {
+		delete(config, "Hostname")
+	}
+	config["Image"] = image
+
+	mode, _ := hostConfig["NetworkMode"].(string)
+	switch {
+	case mode == "" || mode == "default" || mode == "bridge" || mode == "host" || mode == "none":
+	default:
+		result.Warnings = append(result.Warnings, fmt.Sprintf("network mode %s is not migrated, using bridge", mode))
+		hostConfig["NetworkMode"] = "bridge"
+	}
+
+	dataDir := opts.DataDir
+	if dataDir == "" {
+		dataDir = filepath.Join(DefaultMigrationDir, name)
+	}
+
+	for _, mount := range manifest.Mounts {
+		target, remap, err := m.prepareMount(ctx, mount, name, dataDir, opts.PathMap)
+		if err != nil {
+			return nil, err
+		}
+		if target.volume != "" {
+			volumes = append(volumes, target.volume)
+		}
+		if err := restoreInto(filepath.Join(work, mount.Archive.Path), target.dir); err != nil {
+			return nil, err
+		}
+		if remap != nil {
+			result.Remaps = append(result.Remaps, *remap)
+		}
+		// Anonymous volumes have no entry of their own and must be bound
+		// explicitly, or the new container would get an empty one
+		if !replaceMountSource(hostConfig, mount, target.source) && mount.Type == "volume" {
+			binds, _ := hostConfig["Binds"].([]interface{})
+			hostConfig["Binds"] = append(binds, target.source+":"+mount.Destination)
+		}
+	}
+
+	// Binds without bundled data, such as single files, only follow PathMap
+	applyPathMap(hostConfig, opts.PathMap, manifest.Mounts)
+
+	remaps, err := remapPorts(hostConfig, opts.PortMap)
+	if err != nil {
+		return nil, err
+	}
+	result.Remaps = append(result.Remaps, remaps...)
+
+	config["HostConfig"] = hostConfig
+	id, err := m.client.CreateContainer(ctx, name, config)
+	if err != nil {
+		return nil, err
+	}
+	result.ContainerID = id
+	imported = true
+
+	if opts.Start {
+		if err := m.client.StartContainer(ctx, id); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("container created but failed to start: %v", err))
+		} else {
+			result.Started = true
+		}
+	}
+
+	log.Printf("Imported bundle %s as container %s", bundlePath, name)
+	return result, nil
+}
+
+// importImage loads the bundled image unless it is already present and
+// returns the reference the new container should use: the original name when
+// it could be pointed at the bundled image, otherwise the image ID.
+func (m *Manager) importImage(ctx context.Context, manifest *BundleManifest, archivePath string) (string, error) {
+	exists, err := m.client.ImageExists(ctx, manifest.ImageID)
+	if err != nil {
+		return "", err
+	}
+
+	if !exists {
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to open bundled image: %w", err)
+		}
+		defer f.Close()
+
+		if _, err := m.client.LoadImages(ctx, f, nil); err != nil {
+			return "", err
+		}
+	}
+
+	// Images saved by ID carry no tags. An existing tag may point at a
+	// different image, so it is left alone and the ID is used instead.
+	if manifest.Image == "" || strings.Contains(manifest.Image, "@") || strings.HasPrefix(manifest.Image, "sha256:") {
+		return manifest.ImageID, nil
+	}
+	if exists, err := m.client.ImageExists(ctx, manifest.Image); err != nil || exists {
+		return manifest.ImageID, nil
+	}
+	if err := m.client.TagImage(ctx, manifest.ImageID, manifest.Image); err != nil {
+		log.Printf("Failed to tag imported image as %s: %v", manifest.Image, err)
+		return manifest.ImageID, nil
+	}
+	return manifest.Image, nil
+}
+
+type mountTarget struct {
+	// dir is where data is extracted; source is what the container mounts.
+	dir    string
+	source string
+	// volume names the volume created for the mount, if any.
+	volume string
+}
+
+func (m *Manager) prepareMount(ctx context.Context, mount BundleMount, container, dataDir string, pathMap map[string]string) (mountTarget, *Remap, error) {
+	if mount.Type == "volume" && mount.Name != "" {
+		volumeName := mount.Name
+		for i := 2; ; i++ {
+			_, err := m.client.InspectVolume(ctx, volumeName)
+			if errors.Is(err, docker.ErrNotFound) {
+				break
+			}
+			if err != nil {
+				return mountTarget{}, nil, err
+			}
+			volumeName = fmt.Sprintf("%s-%d", mount.Name, i)
+		}
+
+		volume, err := m.client.CreateVolume(ctx, volumeName, map[string]string{"bluenode.migrated-from": container})
+		if err != nil {
+			return mountTarget{}, nil, err
+		}
+
+		target := mountTarget{dir: volume.Mountpoint, source: volumeName, volume: volumeName}
+		if volumeName != mount.Name {
+			return target, &Remap{Kind: "volume", What: mount.Destination, From: mount.Name, To: volumeName}, nil
+		}
+		return target, nil, nil
+	}
+
+	source := mapPath(mount.Source, pathMap)
+	if !dirAvailable(source) {
+		source = filepath.Join(dataDir, mountLabel(docker.MountPoint{Destination: mount.Destination}))
+		if !dirAvailable(source) {
+			return mountTarget{}, nil, fmt.Errorf("%w: host path for %s is taken: %s", docker.ErrConflict, mount.Destination, source)
+		}
+	}
+	if err := os.MkdirAll(source, 0755); err != nil {
+		return mountTarget{}, nil, fmt.Errorf("failed to create bind mount directory: %w", err)
+	}
+
+	target := mountTarget{dir: source, source: source}
+	if source != mount.Source {
+		return target, &Remap{Kind: "path", What: mount.Destination, From: mount.Source, To: source}, nil
+	}
+	return target, nil, nil
+}
+
+func (m *Manager) removeVolumes(ctx context.Context, volumes []string) {
+	for _, name := range volumes {
+		if err := m.client.RemoveVolume(ctx, name, true); err != nil {
+			log.Printf("Failed to remove volume %s after failed import: %v", name, err)
+		}
+	}
+}
+
+// dirAvailable reports whether path is missing or an empty directory.
+func dirAvailable(path string) bool {
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return true
+	}
+	return err == nil && len(entries) == 0
+}
+
+func mapPath(path string, pathMap map[string]string) string {
+	best := ""
+	for from := range pathMap {
+		if (path == from || strings.HasPrefix(path, strings.TrimSuffix(from, "/")+"/")) && len(from) > len(best) {
+			best = from
+		}
+	}
+	if best == "" {
+		return path
+	}
+	return filepath.Join(pathMap[best], strings.TrimPrefix(path, best))
+}
+
+// replaceMountSource points the Binds or Mounts entry for mount at source and
+// reports whether there was one.
+func replaceMountSource(hostConfig map[string]interface{}, mount BundleMount, source string) bool {
+	old := mount.Source
+	if mount.Type == "volume" {
+		old = mount.Name
+	}
+	found := false
+
+	if binds, ok := hostConfig["Binds"].([]interface{}); ok {
+		for i, b := range binds {
+			bind, _ := b.(string)
+			parts := strings.SplitN(bind, ":", 3)
+			if len(parts) >= 2 && parts[0] == old && parts[1] == mount.Destination {
+				parts[0] = source
+				binds[i] = strings.Join(parts, ":")
+				found = true
+			}
+		}
+	}
+
+	if mounts, ok := hostConfig["Mounts"].([]interface{}); ok {
+		for _, entry := range mounts {
+			spec, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if spec["Source"] == old && spec["Target"] == mount.Destination {
+				spec["Source"] = source
+				found = true
+			}
+		}
+	}
+
+	return found
+}
+
+// applyPathMap rewrites bind sources that were not part of the bundle data.
+func applyPathMap(hostConfig map[string]interface{}, pathMap map[string]string, bundled []BundleMount) {
+	if len(pathMap) == 0 {
+		return
+	}
+
+	skip := make(map[string]bool)
+	for _, mount := range bundled {
+		skip[mount.Destination] = true
+	}
+
+	if binds, ok := hostConfig["Binds"].([]interface{}); ok {
+		for i, b := range binds {
+			bind, _ := b.(string)
+			parts := strings.SplitN(bind, ":", 3)
+			if len(parts) >= 2 && strings.HasPrefix(parts[0], "/") && !skip[parts[1]] {
+				parts[0] = mapPath(parts[0], pathMap)
+				binds[i] = strings.Join(parts, ":")
+			}
+		}
+	}
+
+	if mounts, ok := hostConfig["Mounts"].([]interface{}); ok {
+		for _, entry := range mounts {
+			spec, ok := entry.(map[string]interface{})
+			if !ok || spec["Type"] != "bind" {
+				continue
+			}
+			target, _ := spec["Target"].(string)
+			source, _ := spec["Source"].(string)
+			if !skip[target] {
+				spec["Source"] = mapPath(source, pathMap)
+			}
+		}
+	}
+}
+
+// remapPorts applies portMap and moves bindings whose host port is in use to
+// the next free port.
+func remapPorts(hostConfig map[string]interface{}, portMap map[string]string) ([]Remap, error) {
+	bindings, ok := hostConfig["PortBindings"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	containerPorts := make([]string, 0, len(bindings))
+	for port := range bindings {
+		containerPorts = append(containerPorts, port)
+	}
+	sort.Strings(containerPorts)
+
+	taken := make(map[string]bool)
+	var remaps []Remap
+	for _, containerPort := range containerPorts {
+		list, _ := bindings[containerPort].([]interface{})
+		proto := "tcp"
+		if _, p, found := strings.Cut(containerPort, "/"); found {
+			proto = p
+		}
+
+		for _, entry := range list {
+			binding, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			hostPort, _ := binding["HostPort"].(string)
+			if hostPort == "" {
+				continue
+			}
+			hostIP, _ := binding["HostIp"].(string)
+
+			port := hostPort
+			if mapped, ok := portMap[containerPort]; ok {
+				port = mapped
+			} else if mapped, ok := portMap[hostPort]; ok {
+				port = mapped
+			} else if taken[proto+port] || !portFree(proto, hostIP, port) {
+				free, err := nextFreePort(proto, hostIP, port, taken)
+				if err != nil {
+					return nil, err
+				}
+				port = free
+			}
+
+			taken[proto+port] = true
+			if port != hostPort {
+				binding["HostPort"] = port
+				remaps = append(remaps, Remap{Kind: "port", What: containerPort, From: hostPort, To: port})
+			}
+		}
+	}
+
+	return remaps, nil
+}
+
+func nextFreePort(proto, hostIP, from string, taken map[string]bool) (string, error) {
+	start, err := strconv.Atoi(from)
+	if err != nil {
+		return "", fmt.Errorf("invalid host port: %s", from)
+	}
+	for port := start + 1; port <= 65535 && port <= start+maxPortSearch; port++ {
+		candidate := strconv.Itoa(port)
+		if !taken[proto+candidate] && portFree(proto, hostIP, candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no free host port found after %s", from)
+}
+
+// portFree tries to bind the port on the host.
+func portFree(proto, hostIP, port string) bool {
+	address := net.JoinHostPort(hostIP, port)
+	if proto == "udp" {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+func saveImage(ctx context.Context, client *docker.Client, image, path string) (BundleFile, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return BundleFile{}, fmt.Errorf("failed to create image archive: %w", err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := client.SaveImages(ctx, []string{image}, io.MultiWriter(f, hash))
+	if err != nil {
+		return BundleFile{}, err
+	}
+
+	return BundleFile{Path: bundleImage, Size: size, Checksum: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// writeBundle packs the manifest, inspect document and every file listed in
+// the manifest into bundlePath. It writes to a temporary name first so a
+// failed export never leaves a truncated bundle behind.
+func writeBundle(bundlePath, work string, manifest *BundleManifest, raw json.RawMessage) error {
+	partial := bundlePath + ".partial"
+	f, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer os.Remove(partial)
+
+	tw := tar.NewWriter(f)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode bundle manifest: %w", err)
+	}
+
+	err = writeTarBytes(tw, bundleManifest, manifestData)
+	if err == nil {
+		err = writeTarBytes(tw, bundleContainer, raw)
+	}
+	files := []BundleFile{manifest.ImageArchive}
+	for _, mount := range manifest.Mounts {
+		files = append(files, mount.Archive)
+	}
+	for _, file := range files {
+		if err != nil {
+			break
+		}
+		err = writeTarFile(tw, file.Path, filepath.Join(work, file.Path))
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return os.Rename(partial, bundlePath)
+}
+
+func writeTarBytes(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0640,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func writeTarFile(tw *tar.Writer, name, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0640,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// readBundle unpacks a bundle into work, checking each file against the
+// manifest, and returns the manifest and the inspect document.
+func readBundle(bundlePath, work string) (*BundleManifest, json.RawMessage, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != bundleManifest {
+		return nil, nil, fmt.Errorf("not a migration bundle: %s", bundlePath)
+	}
+	var manifest BundleManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode bundle manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > BundleFormatVersion {
+		return nil, nil, fmt.Errorf("unsupported bundle format version %d (supported up to %d)", manifest.FormatVersion, BundleFormatVersion)
+	}
+
+	// File paths come from the bundle and are joined to the work directory,
+	// so only plain relative paths are accepted
+	if manifest.ImageArchive.Path != bundleImage {
+		return nil, nil, fmt.Errorf("invalid image archive path in bundle manifest: %q", manifest.ImageArchive.Path)
+	}
+	expected := map[string]BundleFile{manifest.ImageArchive.Path: manifest.ImageArchive}
+	for _, mount := range manifest.Mounts {
+		path := mount.Archive.Path
+		if !bundlePathValid(path) || path == bundleManifest || path == bundleContainer {
+			return nil, nil, fmt.Errorf("invalid archive path in bundle manifest: %q", path)
+		}
+		if _, ok := expected[path]; ok {
+			return nil, nil, fmt.Errorf("duplicate archive path in bundle manifest: %q", path)
+		}
+		expected[path] = mount.Archive
+	}
+
+	var raw json.RawMessage
+	seen := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		if header.Name == bundleContainer {
+			if raw, err = io.ReadAll(tr); err != nil {
+				return nil, nil, fmt.Errorf("failed to read bundle: %w", err)
+			}
+			continue
+		}
+
+		file, ok := expected[header.Name]
+		if !ok {
+			continue
+		}
+		if err := extractBundleFile(tr, work, file); err != nil {
+			return nil, nil, err
+		}
+		seen[header.Name] = true
+	}
+
+	if raw == nil {
+		return nil, nil, fmt.Errorf("bundle is missing %s", bundleContainer)
+	}
+	for name := range expected {
+		if !seen[name] {
+			return nil, nil, fmt.Errorf("bundle is missing %s", name)
+		}
+	}
+
+	return &manifest, raw, nil
+}
+
+// bundlePathValid reports whether path is a relative path that stays inside
+// the directory it is joined to.
+func bundlePathValid(path string) bool {
+	return path != "" && filepath.IsLocal(path) && filepath.Clean(path) == path
+}
+
+func extractBundleFile(r io.Reader, work string, file BundleFile) error {
+	if !bundlePathValid(file.Path) {
+		return fmt.Errorf("invalid file path in bundle: %q", file.Path)
+	}
+
+	root, err := filepath.EvalSymlinks(work)
+	if err != nil {
+		return fmt.Errorf("failed to resolve work directory: %w", err)
+	}
+	target := filepath.Join(root, file.Path)
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+
+	// Check the resolved directory, then create the file itself without
+	// following links
+	dir, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	if dir != root && !strings.HasPrefix(dir, root+string(os.PathSeparator)) {
+		return fmt.Errorf("bundle file escapes work directory: %s", file.Path)
+	}
+
+	out, err := os.OpenFile(filepath.Join(dir, filepath.Base(target)), os.O_CREATE|os.O_EXCL|os.O_WRONLY|syscall.O_NOFOLLOW, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), r)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", file.Path, err)
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if size != file.Size || checksum != file.Checksum {
+		return fmt.Errorf("bundle file %s is corrupt: checksum %s, expected %s", file.Path, checksum, file.Checksum)
+	}
+	return nil
+}
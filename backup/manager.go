@@ -0,0 +1,336 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Container volume and bind-mount backup and restore
+
+package backup
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/docker"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultBackupDir = "/var/lib/bnhelper/backups"
+
+	ConsistencyNone  = "none"
+	ConsistencyPause = "pause"
+	ConsistencyStop  = "stop"
+
+	stopTimeout = 30
+)
+
+type Manager struct {
+	client *docker.Client
+	store  *database.BackupStore
+	dir    string
+}
+
+type BackupOptions struct {
+	Consistency string
+	// Destinations limits the backup to mounts at these container paths.
+	// All volume and directory bind mounts are backed up when empty.
+	Destinations []string
+}
+
+type RestoreOptions struct {
+	Consistency string
+	// Container is stopped or paused during the restore when set. It defaults
+	// to the container the backup was taken from.
+	Container string
+}
+
+func NewManager(client *docker.Client, store *database.BackupStore, dir string) *Manager {
+	if dir == "" {
+		dir = DefaultBackupDir
+	}
+
+	return &Manager{
+		client: client,
+		store:  store,
+		dir:    dir,
+	}
+}
+
+// Backup archives the selected mounts of a container and catalogs each
+// archive.
+func (m *Manager) Backup(ctx context.Context, containerID string, opts BackupOptions) ([]database.VolumeBackup, error) {
+	if err := validateConsistency(opts.Consistency); err != nil {
+		return nil, err
+	}
+
+	container, err := m.client.InspectContainer(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimPrefix(container.Name, "/")
+
+	mounts := selectMounts(container.Mounts, opts.Destinations)
+	if len(mounts) == 0 {
+		return nil, fmt.Errorf("container %s has no volumes or directory bind mounts to back up", name)
+	}
+
+	resume, err := m.quiesce(ctx, container, opts.Consistency)
+	if err != nil {
+		return nil, err
+	}
+	defer resume()
+
+	// Milliseconds and a random suffix keep concurrent backups of the same
+	// container from colliding on the archive name
+	stamp := time.Now().UTC().Format("20060102-150405.000") + "-" + uuid.New().String()[:8]
+	var backups []database.VolumeBackup
+	for _, mount := range mounts {
+		archivePath := filepath.Join(m.dir, name, fmt.Sprintf("%s-%s.tar.gz", stamp, mountLabel(mount)))
+
+		size, checksum, err := CreateArchive(mount.Source, archivePath)
+		if err != nil {
+			return backups, err
+		}
+
+		backup := database.VolumeBackup{
+			ContainerName: name,
+			MountType:     mount.Type,
+			MountName:     mount.Name,
+			Source:        mount.Source,
+			Destination:   mount.Destination,
+			ArchivePath:   archivePath,
+			Size:          size,
+			Checksum:      checksum,
+			Consistency:   consistencyOrDefault(opts.Consistency),
+		}
+		if err := m.store.Add(&backup); err != nil {
+			os.Remove(archivePath)
+			return backups, err
+		}
+
+		log.Printf("Backed up %s:%s to %s (%d bytes)", name, mount.Destination, archivePath, size)
+		backups = append(backups, backup)
+	}
+
+	return backups, nil
+}
+
+// Restore verifies a backup's checksum and replaces the contents of its
+// volume or bind mount with the archive.
+func (m *Manager) Restore(ctx context.Context, backupID int, opts RestoreOptions) (*database.VolumeBackup, error) {
+	if err := validateConsistency(opts.Consistency); err != nil {
+		return nil, err
+	}
+
+	backup, err := m.store.Get(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := FileChecksum(backup.ArchivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup archive: %w", err)
+	}
+	if checksum != backup.Checksum {
+		return nil, fmt.Errorf("backup archive checksum mismatch: expected %s, got %s", backup.Checksum, checksum)
+	}
+
+	target, err := m.restoreTarget(ctx, backup)
+	if err != nil {
+		return nil, err
+	}
+
+	containerName := opts.Container
+	if containerName == "" {
+		containerName = backup.ContainerName
+	}
+
+	resume := func() {}
+	if consistencyOrDefault(opts.Consistency) != ConsistencyNone {
+		container, err := m.client.InspectContainer(ctx, containerName)
+		if err != nil {
+			return nil, err
+		}
+		if resume, err = m.quiesce(ctx, container, opts.Consistency); err != nil {
+			return nil, err
+		}
+	}
+	defer resume()
+
+	if err := restoreInto(backup.ArchivePath, target); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Restored backup %d into %s", backup.ID, target)
+	return backup, nil
+}
+
+// Delete removes a backup archive and its catalog entry.
+func (m *Manager) Delete(backupID int) error {
+	backup, err := m.store.Get(backupID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(backup.ArchivePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove backup archive: %w", err)
+	}
+
+	return m.store.Delete(backupID)
+}
+
+// restoreTarget resolves where a backup is restored to. Named volumes are
+// looked up again because their mountpoint may have changed.
+func (m *Manager) restoreTarget(ctx context.Context, backup *database.VolumeBackup) (string, error) {
+	if backup.MountType == "volume" && backup.MountName != "" {
+		volume, err := m.client.InspectVolume(ctx, backup.MountName)
+		if err != nil {
+			return "", err
+		}
+		return volume.Mountpoint, nil
+	}
+
+	info, err := os.Stat(backup.Source)
+	if err != nil {
+		return "", fmt.Errorf("restore target unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("restore target is not a directory: %s", backup.Source)
+	}
+	return backup.Source, nil
+}
+
+// quiesce stops or pauses a running container and returns a function that
+// puts it back in its previous state.
+func (m *Manager) quiesce(ctx context.Context, container *docker.ContainerJSON, consistency string) (func(), error) {
+	noop := func() {}
+	if !container.State.Running || container.State.Paused {
+		return noop, nil
+	}
+
+	switch consistencyOrDefault(consistency) {
+	case ConsistencyStop:
+		if err := m.client.StopContainer(ctx, container.ID, stopTimeout); err != nil {
+			return noop, err
+		}
+		return func() {
+			if err := m.client.StartContainer(context.Background(), container.ID); err != nil {
+				log.Printf("Failed to restart container %s after backup: %v", container.Name, err)
+			}
+		}, nil
+	case ConsistencyPause:
+		if err := m.client.PauseContainer(ctx, container.ID); err != nil {
+			return noop, err
+		}
+		return func() {
+			if err := m.client.UnpauseContainer(context.Background(), container.ID); err != nil {
+				log.Printf("Failed to unpause container %s after backup: %v", container.Name, err)
+			}
+		}, nil
+	}
+
+	return noop, nil
+}
+
+func selectMounts(mounts []docker.MountPoint, destinations []string) []docker.MountPoint {
+	wanted := make(map[string]bool)
+	for _, d := range destinations {
+		wanted[d] = true
+	}
+
+	var selected []docker.MountPoint
+	for _, mount := range mounts {
+		if mount.Type != "volume" && mount.Type != "bind" {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[mount.Destination] {
+			continue
+		}
+		// Single-file binds such as /etc/localtime or docker.sock are skipped
+		if info, err := os.Stat(mount.Source); err != nil || !info.IsDir() {
+			continue
+		}
+		selected = append(selected, mount)
+	}
+	return selected
+}
+
+func mountLabel(mount docker.MountPoint) string {
+	if mount.Type == "volume" && mount.Name != "" {
+		return mount.Name
+	}
+	label := strings.Trim(strings.ReplaceAll(mount.Destination, "/", "_"), "_")
+	if label == "" {
+		label = "root"
+	}
+	return label
+}
+
+// restoreInto extracts an archive next to target first, so a corrupt archive
+// leaves the existing data untouched, then swaps the extracted entries in.
+func restoreInto(archivePath, target string) error {
+	staging := target + ".bnhelper-restore"
+	if err := os.RemoveAll(staging); err != nil {
+		return fmt.Errorf("failed to clean staging directory: %w", err)
+	}
+	if err := os.MkdirAll(staging, 0700); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup archive: %w", err)
+	}
+	defer f.Close()
+
+	if err := ExtractArchive(f, staging); err != nil {
+		return err
+	}
+
+	if err := clearDir(target); err != nil {
+		return fmt.Errorf("failed to clear restore target: %w", err)
+	}
+
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.Rename(filepath.Join(staging, entry.Name()), filepath.Join(target, entry.Name())); err != nil {
+			return fmt.Errorf("failed to move restored data into place: %w", err)
+		}
+	}
+	return nil
+}
+
+func clearDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateConsistency(consistency string) error {
+	switch consistency {
+	case "", ConsistencyNone, ConsistencyPause, ConsistencyStop:
+		return nil
+	}
+	return fmt.Errorf("invalid consistency mode: %s", consistency)
+}
+
+func consistencyOrDefault(consistency string) string {
+	if consistency == "" {
+		return ConsistencyNone
+	}
+	return consistency
+}
@@ -0,0 +1,163 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Assistant actions waiting for user confirmation
+
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ActionPending = "pending"
+	// ActionRunning marks an action that was confirmed and is executing, so
+	// a second confirmation cannot run it again.
+	ActionRunning  = "running"
+	ActionExecuted = "executed"
+	ActionFailed   = "failed"
+	ActionRejected = "rejected"
+	ActionExpired  = "expired"
+)
+
+// PendingAction is a mutating tool call requested by the assistant. It only
+// runs once the user confirms it.
+type PendingAction struct {
+	ID        int                    `json:"id"`
+	ActionID  string                 `json:"action_id"`
+	SessionID string                 `json:"session_id"`
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Status    string                 `json:"status"`
+	Result    string                 `json:"result,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+type ActionStore struct {
+	db *AIDB
+}
+
+func NewActionStore(db *AIDB) *ActionStore {
+	return &ActionStore{db: db}
+}
+
+func (as *ActionStore) Create(sessionID, tool string, arguments map[string]interface{}) (*PendingAction, error) {
+	args, err := json.Marshal(arguments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode action arguments: %w", err)
+	}
+
+	actionID := uuid.New().String()
+	query := `
+		INSERT INTO pending_actions (action_id, session_id, tool, arguments)
+		VALUES (?, ?, ?, ?)
+	`
+
+	if _, err := as.db.conn.Exec(query, actionID, sessionID, tool, string(args)); err != nil {
+		return nil, fmt.Errorf("failed to create pending action: %w", err)
+	}
+
+	return as.Get(actionID)
+}
+
+func (as *ActionStore) Get(actionID string) (*PendingAction, error) {
+	query := `
+		SELECT id, action_id, session_id, tool, arguments, status, result, created_at, updated_at
+		FROM pending_actions
+		WHERE action_id = ?
+	`
+
+	action, err := scanPendingAction(as.db.conn.QueryRow(query, actionID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("action not found: %s", actionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get action: %w", err)
+	}
+
+	return action, nil
+}
+
+// List returns the actions of a session, or of all sessions when sessionID
+// is empty, newest first. An empty status matches every status.
+func (as *ActionStore) List(sessionID, status string) ([]PendingAction, error) {
+	query := `
+		SELECT id, action_id, session_id, tool, arguments, status, result, created_at, updated_at
+		FROM pending_actions
+		WHERE (? = '' OR session_id = ?) AND (? = '' OR status = ?)
+		ORDER BY created_at DESC, id DESC
+	`
+
+	rows, err := as.db.conn.Query(query, sessionID, sessionID, status, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []PendingAction{}
+	for rows.Next() {
+		action, err := scanPendingAction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan action: %w", err)
+		}
+		actions = append(actions, *action)
+	}
+
+	return actions, rows.Err()
+}
+
+// Resolve moves a pending action to status and reports whether this caller
+// won; a concurrent confirmation or rejection gets false.
+func (as *ActionStore) Resolve(actionID, status string) (bool, error) {
+	result, err := as.db.conn.Exec(`UPDATE pending_actions SET status = ? WHERE action_id = ? AND status = ?`, status, actionID, ActionPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to update action: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+// Finish records the outcome of a running action.
+func (as *ActionStore) Finish(actionID, status, output string) error {
+	_, err := as.db.conn.Exec(`UPDATE pending_actions SET status = ?, result = ? WHERE action_id = ?`, status, output, actionID)
+	if err != nil {
+		return fmt.Errorf("failed to update action: %w", err)
+	}
+	return nil
+}
+
+func scanPendingAction(row rowScanner) (*PendingAction, error) {
+	var action PendingAction
+	var args string
+	var result sql.NullString
+	err := row.Scan(
+		&action.ID,
+		&action.ActionID,
+		&action.SessionID,
+		&action.Tool,
+		&args,
+		&action.Status,
+		&result,
+		&action.CreatedAt,
+		&action.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(args), &action.Arguments); err != nil {
+		return nil, fmt.Errorf("failed to decode action arguments: %w", err)
+	}
+	action.Result = result.String
+
+	return &action, nil
+}
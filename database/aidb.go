@@ -19,6 +19,9 @@ const (
 
 type AIDB struct {
 	conn *sql.DB
+	// fullText is false when SQLite was built without FTS5, in which case
+	// chat searches fall back to substring matching.
+	fullText bool
 }
 
 func NewAIDB(dbPath string) (*AIDB, error) {
@@ -56,6 +59,15 @@ func (db *AIDB) initialize() error {
 		session_id TEXT NOT NULL UNIQUE,
 		model TEXT NOT NULL,
 		title TEXT,
+		temperature REAL,
+		num_ctx INTEGER,
+		top_p REAL,
+		seed INTEGER,
+		stop TEXT,
+		keep_alive TEXT,
+		summary TEXT,
+		summary_through INTEGER,
+		head_id INTEGER,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -65,6 +77,8 @@ func (db *AIDB) initialize() error {
 		session_id TEXT NOT NULL,
 		role TEXT NOT NULL,
 		content TEXT NOT NULL,
+		attachments TEXT,
+		parent_id INTEGER,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (session_id) REFERENCES chat_sessions(session_id) ON DELETE CASCADE
 	);
@@ -91,11 +105,25 @@ func (db *AIDB) initialize() error {
 		FOREIGN KEY (file_id) REFERENCES indexed_files(id) ON DELETE CASCADE
 	);
 
+	CREATE TABLE IF NOT EXISTS pending_actions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		action_id TEXT NOT NULL UNIQUE,
+		session_id TEXT NOT NULL,
+		tool TEXT NOT NULL,
+		arguments TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		result TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (session_id) REFERENCES chat_sessions(session_id) ON DELETE CASCADE
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_chat_sessions_session_id ON chat_sessions(session_id);
 	CREATE INDEX IF NOT EXISTS idx_chat_messages_session_id ON chat_messages(session_id);
 	CREATE INDEX IF NOT EXISTS idx_indexed_files_path ON indexed_files(file_path);
 	CREATE INDEX IF NOT EXISTS idx_indexed_files_hash ON indexed_files(file_hash);
 	CREATE INDEX IF NOT EXISTS idx_file_chunks_file_id ON file_chunks(file_id);
+	CREATE INDEX IF NOT EXISTS idx_pending_actions_session_id ON pending_actions(session_id);
 
 	CREATE TRIGGER IF NOT EXISTS update_chat_sessions_timestamp 
 	AFTER UPDATE ON chat_sessions
@@ -110,10 +138,123 @@ func (db *AIDB) initialize() error {
 	BEGIN
 		UPDATE indexed_files SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
 	END;
+
+	CREATE TRIGGER IF NOT EXISTS update_pending_actions_timestamp 
+	AFTER UPDATE ON pending_actions
+	FOR EACH ROW
+	BEGIN
+		UPDATE pending_actions SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
+	END;
 	`
 
-	_, err := db.conn.Exec(schema)
-	return err
+	if _, err := db.conn.Exec(schema); err != nil {
+		return err
+	}
+
+	if err := db.migrate(); err != nil {
+		return err
+	}
+
+	// Sessions deleted before DeleteSession removed their messages left
+	// them behind
+	_, err := db.conn.Exec(`
+		DELETE FROM chat_messages WHERE session_id NOT IN (SELECT session_id FROM chat_sessions);
+		DELETE FROM pending_actions WHERE session_id NOT IN (SELECT session_id FROM chat_sessions);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to remove messages of deleted sessions: %w", err)
+	}
+
+	fullText, err := initFullText(db.conn, "chat_messages", "chat_messages_fts", "content")
+	if err != nil {
+		return err
+	}
+	db.fullText = fullText
+	return nil
+}
+
+// migrate adds columns introduced after a table was first created to
+// existing databases. New databases already get them from the schema.
+// backfill runs once, when the column is added, to fill it for existing rows.
+func (db *AIDB) migrate() error {
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+		backfill   string
+	}{
+		{"chat_messages", "attachments", "TEXT", ""},
+		{"chat_sessions", "temperature", "REAL", ""},
+		{"chat_sessions", "num_ctx", "INTEGER", ""},
+		{"chat_sessions", "top_p", "REAL", ""},
+		{"chat_sessions", "seed", "INTEGER", ""},
+		{"chat_sessions", "stop", "TEXT", ""},
+		{"chat_sessions", "keep_alive", "TEXT", ""},
+		{"chat_sessions", "summary", "TEXT", ""},
+		{"chat_sessions", "summary_through", "INTEGER", ""},
+		{"chat_sessions", "head_id", "INTEGER", ""},
+		// Messages stored before branching form a single branch
+		{"chat_messages", "parent_id", "INTEGER", `
+			UPDATE chat_messages SET parent_id = (
+				SELECT MAX(prev.id) FROM chat_messages prev
+				WHERE prev.session_id = chat_messages.session_id AND prev.id < chat_messages.id
+			)`},
+	}
+
+	for _, c := range columns {
+		added, err := db.addColumn(c.table, c.column, c.definition)
+		if err != nil {
+			return err
+		}
+		if added && c.backfill != "" {
+			if _, err := db.conn.Exec(c.backfill); err != nil {
+				return fmt.Errorf("failed to backfill column %s.%s: %w", c.table, c.column, err)
+			}
+		}
+	}
+
+	// Indexes on migrated columns can only be created once they exist
+	if _, err := db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_chat_messages_parent_id ON chat_messages(parent_id)`); err != nil {
+		return fmt.Errorf("failed to create index on chat_messages.parent_id: %w", err)
+	}
+	return nil
+}
+
+// addColumn adds a column unless the table already has it, and reports
+// whether it was added.
+func (db *AIDB) addColumn(table, column, definition string) (bool, error) {
+	rows, err := db.conn.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := db.conn.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return false, fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	log.Printf("Added column %s.%s to AI database", table, column)
+	return true, nil
+}
+
+// FullText reports whether chat searches use the FTS5 index.
+func (db *AIDB) FullText() bool {
+	return db.fullText
 }
 
 func (db *AIDB) Close() error {
@@ -0,0 +1,150 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Volume backup catalog storage
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type VolumeBackup struct {
+	ID            int       `json:"id"`
+	ContainerName string    `json:"container_name"`
+	MountType     string    `json:"mount_type"`
+	MountName     string    `json:"mount_name,omitempty"`
+	Source        string    `json:"source"`
+	Destination   string    `json:"destination"`
+	ArchivePath   string    `json:"archive_path"`
+	Size          int64     `json:"size"`
+	Checksum      string    `json:"checksum"`
+	Consistency   string    `json:"consistency"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type BackupStore struct {
+	db *DB
+}
+
+func NewBackupStore(db *DB) *BackupStore {
+	return &BackupStore{db: db}
+}
+
+func (bs *BackupStore) Add(backup *VolumeBackup) error {
+	query := `
+		INSERT INTO volume_backups (container_name, mount_type, mount_name, source, destination, archive_path, size, checksum, consistency)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := bs.db.conn.Exec(query,
+		backup.ContainerName,
+		backup.MountType,
+		backup.MountName,
+		backup.Source,
+		backup.Destination,
+		backup.ArchivePath,
+		backup.Size,
+		backup.Checksum,
+		backup.Consistency,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add volume backup: %w", err)
+	}
+
+	id, _ := result.LastInsertId()
+	backup.ID = int(id)
+	backup.CreatedAt = time.Now()
+
+	return nil
+}
+
+func (bs *BackupStore) Get(id int) (*VolumeBackup, error) {
+	query := `
+		SELECT id, container_name, mount_type, mount_name, source, destination, archive_path, size, checksum, consistency, created_at
+		FROM volume_backups
+		WHERE id = ?
+	`
+
+	backup, err := scanVolumeBackup(bs.db.conn.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("volume backup not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume backup: %w", err)
+	}
+
+	return backup, nil
+}
+
+func (bs *BackupStore) List(containerName string, limit int) ([]VolumeBackup, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `
+		SELECT id, container_name, mount_type, mount_name, source, destination, archive_path, size, checksum, consistency, created_at
+		FROM volume_backups
+		WHERE (? = '' OR container_name = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := bs.db.conn.Query(query, containerName, containerName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volume backups: %w", err)
+	}
+	defer rows.Close()
+
+	var backups []VolumeBackup
+	for rows.Next() {
+		backup, err := scanVolumeBackup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan volume backup: %w", err)
+		}
+		backups = append(backups, *backup)
+	}
+
+	return backups, rows.Err()
+}
+
+func (bs *BackupStore) Delete(id int) error {
+	query := `DELETE FROM volume_backups WHERE id = ?`
+
+	result, err := bs.db.conn.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete volume backup: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("volume backup not found: %d", id)
+	}
+
+	return nil
+}
+
+func scanVolumeBackup(row rowScanner) (*VolumeBackup, error) {
+	var backup VolumeBackup
+	err := row.Scan(
+		&backup.ID,
+		&backup.ContainerName,
+		&backup.MountType,
+		&backup.MountName,
+		&backup.Source,
+		&backup.Destination,
+		&backup.ArchivePath,
+		&backup.Size,
+		&backup.Checksum,
+		&backup.Consistency,
+		&backup.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
@@ -13,20 +13,79 @@ import (
 )
 
 type ChatSession struct {
-	ID        int       `json:"id"`
-	SessionID string    `json:"session_id"`
-	Model     string    `json:"model"`
-	Title     string    `json:"title,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int               `json:"id"`
+	SessionID string            `json:"session_id"`
+	Model     string            `json:"model"`
+	Title     string            `json:"title,omitempty"`
+	Options   GenerationOptions `json:"options"`
+	// Summary condenses the messages up to SummaryThrough, which are no
+	// longer sent to the model.
+	Summary        string    `json:"summary,omitempty"`
+	SummaryThrough int       `json:"summary_through,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
+// GenerationOptions are the sampling settings of a chat. Unset fields use
+// Ollama's defaults.
+type GenerationOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumCtx      *int     `json:"num_ctx,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	KeepAlive   string   `json:"keep_alive,omitempty"`
+}
+
+// Merge returns the options with every field set in override replacing the
+// session's value.
+func (o GenerationOptions) Merge(override GenerationOptions) GenerationOptions {
+	if override.Temperature != nil {
+		o.Temperature = override.Temperature
+	}
+	if override.NumCtx != nil {
+		o.NumCtx = override.NumCtx
+	}
+	if override.TopP != nil {
+		o.TopP = override.TopP
+	}
+	if override.Seed != nil {
+		o.Seed = override.Seed
+	}
+	if override.Stop != nil {
+		o.Stop = override.Stop
+	}
+	if override.KeepAlive != "" {
+		o.KeepAlive = override.KeepAlive
+	}
+	return o
+}
+
+// ChatMessage is one message of a session. Messages form a tree through
+// ParentID: editing or regenerating a message adds a sibling instead of
+// replacing it, and the session's head selects the branch that is shown and
+// continued.
 type ChatMessage struct {
-	ID        int       `json:"id"`
-	SessionID string    `json:"session_id"`
-	Role      string    `json:"role"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          int          `json:"id"`
+	SessionID   string       `json:"session_id"`
+	ParentID    int          `json:"parent_id,omitempty"`
+	Role        string       `json:"role"`
+	Content     string       `json:"content"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Alternatives lists the IDs of all versions of this message, oldest
+	// first, when there is more than one.
+	Alternatives []int     `json:"alternatives,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Attachment references a file sent with a message. Uploaded files are
+// stored by the helper; others point at the file on the NAS.
+type Attachment struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	Uploaded bool   `json:"uploaded,omitempty"`
 }
 
 type ChatStore struct {
@@ -37,15 +96,26 @@ func NewChatStore(db *AIDB) *ChatStore {
 	return &ChatStore{db: db}
 }
 
-func (cs *ChatStore) CreateSession(model, title string) (*ChatSession, error) {
+func (cs *ChatStore) CreateSession(model, title string, options GenerationOptions) (*ChatSession, error) {
 	sessionID := uuid.New().String()
 
+	var stop sql.NullString
+	if len(options.Stop) > 0 {
+		data, err := json.Marshal(options.Stop)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode stop sequences: %w", err)
+		}
+		stop = sql.NullString{String: string(data), Valid: true}
+	}
+
 	query := `
-		INSERT INTO chat_sessions (session_id, model, title)
-		VALUES (?, ?, ?)
+		INSERT INTO chat_sessions (session_id, model, title, temperature, num_ctx, top_p, seed, stop, keep_alive)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	result, err := cs.db.conn.Exec(query, sessionID, model, title)
+	result, err := cs.db.conn.Exec(query, sessionID, model, title,
+		options.Temperature, options.NumCtx, options.TopP, options.Seed, stop,
+		sql.NullString{String: options.KeepAlive, Valid: options.KeepAlive != ""})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create chat session: %w", err)
 	}
@@ -57,6 +127,7 @@ func (cs *ChatStore) CreateSession(model, title string) (*ChatSession, error) {
 		SessionID: sessionID,
 		Model:     model,
 		Title:     title,
+		Options:   options,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}, nil
@@ -64,21 +135,12 @@ func (cs *ChatStore) CreateSession(model, title string) (*ChatSession, error) {
 
 func (cs *ChatStore) GetSession(sessionID string) (*ChatSession, error) {
 	query := `
-		SELECT id, session_id, model, title, created_at, updated_at
+		SELECT id, session_id, model, title, temperature, num_ctx, top_p, seed, stop, keep_alive, summary, summary_through, created_at, updated_at
 		FROM chat_sessions
 		WHERE session_id = ?
 	`
 
-	var session ChatSession
-	err := cs.db.conn.QueryRow(query, sessionID).Scan(
-		&session.ID,
-		&session.SessionID,
-		&session.Model,
-		&session.Title,
-		&session.CreatedAt,
-		&session.UpdatedAt,
-	)
-
+	session, err := scanChatSession(cs.db.conn.QueryRow(query, sessionID))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("chat session not found: %s", sessionID)
 	}
@@ -86,7 +148,7 @@ func (cs *ChatStore) GetSession(sessionID string) (*ChatSession, error) {
 		return nil, fmt.Errorf("failed to get chat session: %w", err)
 	}
 
-	return &session, nil
+	return session, nil
 }
 
 func (cs *ChatStore) ListSessions(limit int) ([]ChatSession, error) {
@@ -95,7 +157,7 @@ func (cs *ChatStore) ListSessions(limit int) ([]ChatSession, error) {
 	}
 
 	query := `
-		SELECT id, session_id, model, title, created_at, updated_at
+		SELECT id, session_id, model, title, temperature, num_ctx, top_p, seed, stop, keep_alive, summary, summary_through, created_at, updated_at
 		FROM chat_sessions
 		ORDER BY updated_at DESC
 		LIMIT ?
@@ -109,28 +171,26 @@ func (cs *ChatStore) ListSessions(limit int) ([]ChatSession, error) {
 
 	var sessions []ChatSession
 	for rows.Next() {
-		var session ChatSession
-		err := rows.Scan(
-			&session.ID,
-			&session.SessionID,
-			&session.Model,
-			&session.Title,
-			&session.CreatedAt,
-			&session.UpdatedAt,
-		)
+		session, err := scanChatSession(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan chat session: %w", err)
 		}
-		sessions = append(sessions, session)
+		sessions = append(sessions, *session)
 	}
 
 	return sessions, rows.Err()
 }
 
+// DeleteSession removes a session together with its messages and pending
+// actions. Foreign keys are not enforced, so nothing cascades on its own.
 func (cs *ChatStore) DeleteSession(sessionID string) error {
-	query := `DELETE FROM chat_sessions WHERE session_id = ?`
+	tx, err := cs.db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	result, err := cs.db.conn.Exec(query, sessionID)
+	result, err := tx.Exec(`DELETE FROM chat_sessions WHERE session_id = ?`, sessionID)
 	if err != nil {
 		return fmt.Errorf("failed to delete chat session: %w", err)
 	}
@@ -144,43 +204,201 @@ func (cs *ChatStore) DeleteSession(sessionID string) error {
 		return fmt.Errorf("chat session not found: %s", sessionID)
 	}
 
+	if _, err := tx.Exec(`DELETE FROM chat_messages WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete chat messages: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM pending_actions WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("failed to delete pending actions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete chat session: %w", err)
+	}
 	return nil
 }
 
 func (cs *ChatStore) AddMessage(sessionID, role, content string) (*ChatMessage, error) {
+	return cs.AddMessageWithAttachments(sessionID, role, content, nil)
+}
+
+// AddMessageWithAttachments appends a message to the current branch of the
+// session.
+func (cs *ChatStore) AddMessageWithAttachments(sessionID, role, content string, attachments []Attachment) (*ChatMessage, error) {
+	return cs.addMessage(sessionID, nil, role, content, attachments)
+}
+
+// AddReply adds a message after parentID, or as a first message when
+// parentID is 0, and makes its branch the current one. Other messages after
+// parentID are kept as alternatives.
+func (cs *ChatStore) AddReply(sessionID string, parentID int, role, content string, attachments []Attachment) (*ChatMessage, error) {
+	return cs.addMessage(sessionID, &parentID, role, content, attachments)
+}
+
+func (cs *ChatStore) addMessage(sessionID string, parentID *int, role, content string, attachments []Attachment) (*ChatMessage, error) {
+	var attachmentsJSON sql.NullString
+	if len(attachments) > 0 {
+		data, err := json.Marshal(attachments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode attachments: %w", err)
+		}
+		attachmentsJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	tx, err := cs.db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var parent sql.NullInt64
+	if parentID == nil {
+		parent, err = headID(tx, sessionID)
+		if err != nil {
+			return nil, err
+		}
+	} else if *parentID != 0 {
+		var exists int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM chat_messages WHERE id = ? AND session_id = ?`, *parentID, sessionID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chat message: %w", err)
+		}
+		if exists == 0 {
+			return nil, fmt.Errorf("chat message not found: %d", *parentID)
+		}
+		parent = sql.NullInt64{Int64: int64(*parentID), Valid: true}
+	}
+
 	query := `
-		INSERT INTO chat_messages (session_id, role, content)
-		VALUES (?, ?, ?)
+		INSERT INTO chat_messages (session_id, parent_id, role, content, attachments)
+		VALUES (?, ?, ?, ?, ?)
 	`
 
-	result, err := cs.db.conn.Exec(query, sessionID, role, content)
+	result, err := tx.Exec(query, sessionID, parent, role, content, attachmentsJSON)
 	if err != nil {
 		return nil, fmt.Errorf("failed to add chat message: %w", err)
 	}
 
 	id, _ := result.LastInsertId()
 
-	updateQuery := `UPDATE chat_sessions SET updated_at = CURRENT_TIMESTAMP WHERE session_id = ?`
-	cs.db.conn.Exec(updateQuery, sessionID)
+	updateQuery := `UPDATE chat_sessions SET head_id = ?, updated_at = CURRENT_TIMESTAMP WHERE session_id = ?`
+	if _, err := tx.Exec(updateQuery, id, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to update chat session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit chat message: %w", err)
+	}
 
 	return &ChatMessage{
-		ID:        int(id),
-		SessionID: sessionID,
-		Role:      role,
-		Content:   content,
-		CreatedAt: time.Now(),
+		ID:          int(id),
+		SessionID:   sessionID,
+		ParentID:    int(parent.Int64),
+		Role:        role,
+		Content:     content,
+		Attachments: attachments,
+		CreatedAt:   time.Now(),
 	}, nil
 }
 
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// headID returns the last message of the current branch. Sessions without
+// an explicit head continue from their newest message.
+func headID(q queryRower, sessionID string) (sql.NullInt64, error) {
+	query := `
+		SELECT COALESCE(head_id, (SELECT MAX(id) FROM chat_messages WHERE session_id = ?))
+		FROM chat_sessions
+		WHERE session_id = ?
+	`
+
+	var head sql.NullInt64
+	err := q.QueryRow(query, sessionID, sessionID).Scan(&head)
+	if err == sql.ErrNoRows {
+		return head, fmt.Errorf("chat session not found: %s", sessionID)
+	}
+	if err != nil {
+		return head, fmt.Errorf("failed to get chat session head: %w", err)
+	}
+	return head, nil
+}
+
+func (cs *ChatStore) GetMessage(sessionID string, id int) (*ChatMessage, error) {
+	query := `
+		SELECT id, session_id, parent_id, role, content, attachments, created_at
+		FROM chat_messages
+		WHERE session_id = ? AND id = ?
+	`
+
+	msg, err := scanChatMessage(cs.db.conn.QueryRow(query, sessionID, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("chat message not found: %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat message: %w", err)
+	}
+
+	return msg, nil
+}
+
+// GetMessages returns the current branch of the session, oldest first.
 func (cs *ChatStore) GetMessages(sessionID string) ([]ChatMessage, error) {
+	head, err := headID(cs.db.conn, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !head.Valid {
+		return nil, nil
+	}
+	return cs.GetBranch(sessionID, int(head.Int64))
+}
+
+// GetBranch returns the messages leading up to and including the message
+// with ID leafID, oldest first.
+func (cs *ChatStore) GetBranch(sessionID string, leafID int) ([]ChatMessage, error) {
+	query := `
+		WITH RECURSIVE branch(id) AS (
+			SELECT id FROM chat_messages WHERE id = ? AND session_id = ?
+			UNION ALL
+			SELECT m.parent_id FROM chat_messages m JOIN branch b ON m.id = b.id
+			WHERE m.parent_id IS NOT NULL
+		)
+		SELECT id, session_id, parent_id, role, content, attachments, created_at
+		FROM chat_messages
+		WHERE id IN (SELECT id FROM branch)
+		ORDER BY id ASC
+	`
+
+	messages, err := cs.queryMessages(query, leafID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("chat message not found: %d", leafID)
+	}
+
+	if err := cs.setAlternatives(sessionID, messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// GetAllMessages returns every message of the session including other
+// branches, oldest first.
+func (cs *ChatStore) GetAllMessages(sessionID string) ([]ChatMessage, error) {
 	query := `
-		SELECT id, session_id, role, content, created_at
+		SELECT id, session_id, parent_id, role, content, attachments, created_at
 		FROM chat_messages
 		WHERE session_id = ?
-		ORDER BY created_at ASC
+		ORDER BY id ASC
 	`
 
-	rows, err := cs.db.conn.Query(query, sessionID)
+	return cs.queryMessages(query, sessionID)
+}
+
+func (cs *ChatStore) queryMessages(query string, args ...interface{}) ([]ChatMessage, error) {
+	rows, err := cs.db.conn.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chat messages: %w", err)
 	}
@@ -188,23 +406,101 @@ func (cs *ChatStore) GetMessages(sessionID string) ([]ChatMessage, error) {
 
 	var messages []ChatMessage
 	for rows.Next() {
-		var msg ChatMessage
-		err := rows.Scan(
-			&msg.ID,
-			&msg.SessionID,
-			&msg.Role,
-			&msg.Content,
-			&msg.CreatedAt,
-		)
+		msg, err := scanChatMessage(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan chat message: %w", err)
 		}
-		messages = append(messages, msg)
+		messages = append(messages, *msg)
 	}
 
 	return messages, rows.Err()
 }
 
+// setAlternatives fills in the other versions of each message in a branch.
+func (cs *ChatStore) setAlternatives(sessionID string, messages []ChatMessage) error {
+	rows, err := cs.db.conn.Query(`SELECT id, parent_id FROM chat_messages WHERE session_id = ? ORDER BY id ASC`, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	children := make(map[int][]int)
+	for rows.Next() {
+		var id int
+		var parentID sql.NullInt64
+		if err := rows.Scan(&id, &parentID); err != nil {
+			return fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		children[int(parentID.Int64)] = append(children[int(parentID.Int64)], id)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get chat messages: %w", err)
+	}
+
+	for i := range messages {
+		if siblings := children[messages[i].ParentID]; len(siblings) > 1 {
+			messages[i].Alternatives = siblings
+		}
+	}
+	return nil
+}
+
+// SelectBranch makes the branch through the message with ID messageID the
+// current one. When the message has replies, the branch continues with the
+// newest of them.
+func (cs *ChatStore) SelectBranch(sessionID string, messageID int) error {
+	query := `
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM chat_messages WHERE id = ? AND session_id = ?
+			UNION ALL
+			SELECT m.id FROM chat_messages m JOIN subtree s ON m.parent_id = s.id
+		)
+		SELECT MAX(id) FROM subtree
+	`
+
+	// Replies always have higher IDs than their parent, so the newest
+	// message below messageID is a leaf
+	var head sql.NullInt64
+	if err := cs.db.conn.QueryRow(query, messageID, sessionID).Scan(&head); err != nil {
+		return fmt.Errorf("failed to get chat branch: %w", err)
+	}
+	if !head.Valid {
+		return fmt.Errorf("chat message not found: %d", messageID)
+	}
+
+	if _, err := cs.db.conn.Exec(`UPDATE chat_sessions SET head_id = ? WHERE session_id = ?`, head.Int64, sessionID); err != nil {
+		return fmt.Errorf("failed to select chat branch: %w", err)
+	}
+	return nil
+}
+
+func scanChatMessage(row rowScanner) (*ChatMessage, error) {
+	var msg ChatMessage
+	var parentID sql.NullInt64
+	var attachments sql.NullString
+	err := row.Scan(
+		&msg.ID,
+		&msg.SessionID,
+		&parentID,
+		&msg.Role,
+		&msg.Content,
+		&attachments,
+		&msg.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.ParentID = int(parentID.Int64)
+	if attachments.Valid && attachments.String != "" {
+		if err := json.Unmarshal([]byte(attachments.String), &msg.Attachments); err != nil {
+			return nil, fmt.Errorf("failed to decode attachments: %w", err)
+		}
+	}
+
+	return &msg, nil
+}
+
 func (cs *ChatStore) UpdateSessionTitle(sessionID, title string) error {
 	query := `UPDATE chat_sessions SET title = ? WHERE session_id = ?`
 
@@ -225,6 +521,195 @@ func (cs *ChatStore) UpdateSessionTitle(sessionID, title string) error {
 	return nil
 }
 
+// SetGeneratedTitle sets the title of a session that has none yet. A title
+// set in the meantime, for example by a rename, is kept.
+func (cs *ChatStore) SetGeneratedTitle(sessionID, title string) error {
+	query := `UPDATE chat_sessions SET title = ? WHERE session_id = ? AND (title IS NULL OR title = '')`
+
+	if _, err := cs.db.conn.Exec(query, title, sessionID); err != nil {
+		return fmt.Errorf("failed to update session title: %w", err)
+	}
+	return nil
+}
+
+// ImportSession stores a session and its messages as given, keeping their
+// timestamps. Message IDs are assigned anew. ID and ParentID of the given
+// messages link them into branches and only need to be unique within the
+// import; a parent must come before its replies. Without IDs the messages
+// form a single branch. The last message becomes the head of the session.
+func (cs *ChatStore) ImportSession(session ChatSession, messages []ChatMessage) (*ChatSession, error) {
+	var stop sql.NullString
+	if len(session.Options.Stop) > 0 {
+		data, err := json.Marshal(session.Options.Stop)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode stop sequences: %w", err)
+		}
+		stop = sql.NullString{String: string(data), Valid: true}
+	}
+
+	tx, err := cs.db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO chat_sessions (session_id, model, title, temperature, num_ctx, top_p, seed, stop, keep_alive, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, session.SessionID, session.Model, session.Title,
+		session.Options.Temperature, session.Options.NumCtx, session.Options.TopP, session.Options.Seed, stop,
+		sql.NullString{String: session.Options.KeepAlive, Valid: session.Options.KeepAlive != ""},
+		sqliteTime(session.CreatedAt), sqliteTime(session.UpdatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to import chat session: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO chat_messages (session_id, parent_id, role, content, attachments, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare message insert: %w", err)
+	}
+	defer stmt.Close()
+
+	linear := true
+	for _, msg := range messages {
+		if msg.ID != 0 {
+			linear = false
+			break
+		}
+	}
+
+	// ids maps the IDs of the import to the stored ones
+	ids := make(map[int]int64, len(messages))
+	var previous sql.NullInt64
+	for i, msg := range messages {
+		parent := previous
+		if !linear {
+			parent = sql.NullInt64{}
+			if msg.ParentID != 0 {
+				id, ok := ids[msg.ParentID]
+				if !ok {
+					return nil, fmt.Errorf("message %d: parent %d not found before it", i+1, msg.ParentID)
+				}
+				parent = sql.NullInt64{Int64: id, Valid: true}
+			}
+		}
+
+		var attachments sql.NullString
+		if len(msg.Attachments) > 0 {
+			data, err := json.Marshal(msg.Attachments)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode attachments: %w", err)
+			}
+			attachments = sql.NullString{String: string(data), Valid: true}
+		}
+
+		result, err := stmt.Exec(session.SessionID, parent, msg.Role, msg.Content, attachments, sqliteTime(msg.CreatedAt))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import chat message: %w", err)
+		}
+		id, _ := result.LastInsertId()
+		if !linear {
+			if _, ok := ids[msg.ID]; ok {
+				return nil, fmt.Errorf("message %d: duplicate ID %d", i+1, msg.ID)
+			}
+			ids[msg.ID] = id
+		}
+		previous = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	return cs.GetSession(session.SessionID)
+}
+
+// UpdateSessionSummary stores the summary of the messages up to and
+// including the message with ID through.
+func (cs *ChatStore) UpdateSessionSummary(sessionID, summary string, through int) error {
+	query := `UPDATE chat_sessions SET summary = ?, summary_through = ? WHERE session_id = ?`
+
+	result, err := cs.db.conn.Exec(query, summary, through, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to update session summary: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("chat session not found: %s", sessionID)
+	}
+
+	return nil
+}
+
+func scanChatSession(row rowScanner) (*ChatSession, error) {
+	var session ChatSession
+	var temperature, topP sql.NullFloat64
+	var numCtx, seed sql.NullInt64
+	var stop, keepAlive, summary sql.NullString
+	var summaryThrough sql.NullInt64
+	err := row.Scan(
+		&session.ID,
+		&session.SessionID,
+		&session.Model,
+		&session.Title,
+		&temperature,
+		&numCtx,
+		&topP,
+		&seed,
+		&stop,
+		&keepAlive,
+		&summary,
+		&summaryThrough,
+		&session.CreatedAt,
+		&session.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if temperature.Valid {
+		session.Options.Temperature = &temperature.Float64
+	}
+	if numCtx.Valid {
+		n := int(numCtx.Int64)
+		session.Options.NumCtx = &n
+	}
+	if topP.Valid {
+		session.Options.TopP = &topP.Float64
+	}
+	if seed.Valid {
+		n := int(seed.Int64)
+		session.Options.Seed = &n
+	}
+	if stop.Valid && stop.String != "" {
+		if err := json.Unmarshal([]byte(stop.String), &session.Options.Stop); err != nil {
+			return nil, fmt.Errorf("failed to decode stop sequences: %w", err)
+		}
+	}
+	session.Options.KeepAlive = keepAlive.String
+	session.Summary = summary.String
+	session.SummaryThrough = int(summaryThrough.Int64)
+
+	return &session, nil
+}
+
+// sqliteTime formats t like CURRENT_TIMESTAMP so imported rows sort with
+// the rest.
+func sqliteTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
 func float64SliceToBytes(slice []float64) ([]byte, error) {
 	return json.Marshal(slice)
 }
@@ -0,0 +1,186 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Full-text search across chat history
+
+package database
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	DefaultChatSearchLimit = 50
+	MaxChatSearchLimit     = 500
+)
+
+// snippetStart and snippetEnd mark matches in the snippets built by SQLite.
+// They cannot occur in escaped text, so the snippet can be escaped before
+// they are turned into <mark> tags.
+const (
+	snippetStart = "\x02"
+	snippetEnd   = "\x03"
+)
+
+// snippetContext is how many characters of a message are shown around the
+// first match when SQLite has no FTS5.
+const snippetContext = 60
+
+type ChatSearchQuery struct {
+	Text  string
+	Model string
+	Role  string
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+// ChatSearchResult is a message matching a search. Snippet is HTML: the
+// message text around the matches, escaped, with matches in <mark> tags.
+type ChatSearchResult struct {
+	MessageID int       `json:"message_id"`
+	SessionID string    `json:"session_id"`
+	Title     string    `json:"title,omitempty"`
+	Model     string    `json:"model"`
+	Role      string    `json:"role"`
+	Snippet   string    `json:"snippet"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Search returns the messages of all sessions that contain every word of
+// the query, newest first. Messages in other branches are included; system
+// prompts are not.
+func (cs *ChatStore) Search(q ChatSearchQuery) ([]ChatSearchResult, error) {
+	words := strings.Fields(q.Text)
+	if len(words) == 0 {
+		return []ChatSearchResult{}, nil
+	}
+
+	conditions := []string{`m.role != 'system'`}
+	var args []interface{}
+
+	from := `chat_messages m`
+	snippet := `m.content`
+	if cs.db.fullText {
+		from = `chat_messages_fts JOIN chat_messages m ON m.id = chat_messages_fts.rowid`
+		snippet = `snippet(chat_messages_fts, 0, char(2), char(3), '…', 24)`
+		conditions = append(conditions, `chat_messages_fts MATCH ?`)
+		args = append(args, ftsQuery(words))
+	} else {
+		for _, word := range words {
+			conditions = append(conditions, `m.content LIKE ? ESCAPE '\'`)
+			args = append(args, "%"+likeEscaper.Replace(word)+"%")
+		}
+	}
+	if q.Model != "" {
+		conditions = append(conditions, `s.model = ?`)
+		args = append(args, q.Model)
+	}
+	if q.Role != "" {
+		conditions = append(conditions, `m.role = ?`)
+		args = append(args, q.Role)
+	}
+	if !q.From.IsZero() {
+		conditions = append(conditions, `m.created_at >= ?`)
+		args = append(args, sqliteTime(q.From))
+	}
+	if !q.To.IsZero() {
+		conditions = append(conditions, `m.created_at <= ?`)
+		args = append(args, sqliteTime(q.To))
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultChatSearchLimit
+	}
+	if limit > MaxChatSearchLimit {
+		limit = MaxChatSearchLimit
+	}
+
+	query := `
+		SELECT m.id, m.session_id, COALESCE(s.title, ''), s.model, m.role, ` + snippet + `, m.created_at
+		FROM ` + from + `
+		JOIN chat_sessions s ON s.session_id = m.session_id
+		WHERE ` + strings.Join(conditions, ` AND `) + `
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT ?
+	`
+	args = append(args, limit)
+
+	rows, err := cs.db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	results := []ChatSearchResult{}
+	for rows.Next() {
+		var result ChatSearchResult
+		var text string
+		err := rows.Scan(
+			&result.MessageID,
+			&result.SessionID,
+			&result.Title,
+			&result.Model,
+			&result.Role,
+			&text,
+			&result.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan chat search result: %w", err)
+		}
+
+		if !cs.db.fullText {
+			text = markMatches(text, words)
+		}
+		result.Snippet = snippetHTML(text)
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+func snippetHTML(text string) string {
+	text = html.EscapeString(strings.ToValidUTF8(text, "�"))
+	text = strings.ReplaceAll(text, snippetStart, "<mark>")
+	return strings.ReplaceAll(text, snippetEnd, "</mark>")
+}
+
+// markMatches cuts a message down to the text around its first match and
+// marks the words in it, like the snippets of the FTS5 index.
+func markMatches(content string, words []string) string {
+	patterns := make([]string, len(words))
+	for i, word := range words {
+		patterns[i] = regexp.QuoteMeta(word)
+	}
+	re := regexp.MustCompile(`(?i)` + strings.Join(patterns, "|"))
+
+	content = strings.NewReplacer(snippetStart, "", snippetEnd, "").Replace(content)
+
+	if loc := re.FindStringIndex(content); loc != nil {
+		start := loc[0]
+		for n := 0; n < snippetContext && start > 0; n++ {
+			_, size := utf8.DecodeLastRuneInString(content[:start])
+			start -= size
+		}
+		end := loc[1]
+		for n := 0; n < 2*snippetContext && end < len(content); n++ {
+			_, size := utf8.DecodeRuneInString(content[end:])
+			end += size
+		}
+
+		excerpt := content[start:end]
+		if start > 0 {
+			excerpt = "…" + excerpt
+		}
+		if end < len(content) {
+			excerpt += "…"
+		}
+		content = excerpt
+	}
+
+	return re.ReplaceAllString(content, snippetStart+"$0"+snippetEnd)
+}
@@ -61,7 +61,105 @@ func (db *DB) initialize() error {
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
+	CREATE TABLE IF NOT EXISTS health_policies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container_name TEXT NOT NULL UNIQUE,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		unhealthy_threshold INTEGER NOT NULL DEFAULT 3,
+		max_restarts_per_hour INTEGER NOT NULL DEFAULT 5,
+		backoff_seconds INTEGER NOT NULL DEFAULT 30,
+		max_backoff_seconds INTEGER NOT NULL DEFAULT 600,
+		restart_on_exit BOOLEAN NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS health_actions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container_name TEXT NOT NULL,
+		container_id TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		success BOOLEAN NOT NULL DEFAULT 1,
+		error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS registry_credentials (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		registry TEXT NOT NULL UNIQUE,
+		username TEXT NOT NULL,
+		secret BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS volume_backups (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container_name TEXT NOT NULL,
+		mount_type TEXT NOT NULL,
+		mount_name TEXT NOT NULL DEFAULT '',
+		source TEXT NOT NULL,
+		destination TEXT NOT NULL,
+		archive_path TEXT NOT NULL UNIQUE,
+		size INTEGER NOT NULL,
+		checksum TEXT NOT NULL,
+		consistency TEXT NOT NULL DEFAULT 'none',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS docker_endpoints (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE,
+		url TEXT NOT NULL,
+		tls_verify BOOLEAN NOT NULL DEFAULT 1,
+		ca_cert TEXT NOT NULL DEFAULT '',
+		client_cert TEXT NOT NULL DEFAULT '',
+		client_key BLOB,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS startup_entries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container_name TEXT NOT NULL UNIQUE,
+		start_group INTEGER NOT NULL DEFAULT 0,
+		wait_healthy BOOLEAN NOT NULL DEFAULT 1,
+		timeout_seconds INTEGER NOT NULL DEFAULT 120,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS startup_dependencies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container_name TEXT NOT NULL,
+		depends_on TEXT NOT NULL,
+		UNIQUE(container_name, depends_on)
+	);
+
+	CREATE TABLE IF NOT EXISTS container_metrics (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container_name TEXT NOT NULL,
+		resolution INTEGER NOT NULL,
+		bucket INTEGER NOT NULL,
+		samples INTEGER NOT NULL DEFAULT 0,
+		cpu_percent REAL NOT NULL DEFAULT 0,
+		cpu_max REAL NOT NULL DEFAULT 0,
+		memory_bytes INTEGER NOT NULL DEFAULT 0,
+		memory_max INTEGER NOT NULL DEFAULT 0,
+		memory_limit INTEGER NOT NULL DEFAULT 0,
+		net_rx_bytes INTEGER NOT NULL DEFAULT 0,
+		net_tx_bytes INTEGER NOT NULL DEFAULT 0,
+		block_read_bytes INTEGER NOT NULL DEFAULT 0,
+		block_write_bytes INTEGER NOT NULL DEFAULT 0,
+		UNIQUE(container_name, resolution, bucket)
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_configurations_key ON configurations(key);
+	CREATE INDEX IF NOT EXISTS idx_volume_backups_container ON volume_backups(container_name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_health_actions_container ON health_actions(container_name, created_at);
+	CREATE INDEX IF NOT EXISTS idx_container_metrics_bucket ON container_metrics(resolution, bucket);
 
 	CREATE TRIGGER IF NOT EXISTS update_configurations_timestamp 
 	AFTER UPDATE ON configurations
@@ -69,6 +167,34 @@ func (db *DB) initialize() error {
 	BEGIN
 		UPDATE configurations SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
 	END;
+
+	CREATE TRIGGER IF NOT EXISTS update_startup_entries_timestamp 
+	AFTER UPDATE ON startup_entries
+	FOR EACH ROW
+	BEGIN
+		UPDATE startup_entries SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS update_health_policies_timestamp 
+	AFTER UPDATE ON health_policies
+	FOR EACH ROW
+	BEGIN
+		UPDATE health_policies SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS update_registry_credentials_timestamp 
+	AFTER UPDATE ON registry_credentials
+	FOR EACH ROW
+	BEGIN
+		UPDATE registry_credentials SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS update_docker_endpoints_timestamp 
+	AFTER UPDATE ON docker_endpoints
+	FOR EACH ROW
+	BEGIN
+		UPDATE docker_endpoints SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
+	END;
 	`
 
 	_, err := db.conn.Exec(schema)
@@ -0,0 +1,163 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Named Docker endpoint storage
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type DockerEndpoint struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	TLSVerify  bool      `json:"tls_verify"`
+	CACert     string    `json:"ca_cert,omitempty"`
+	ClientCert string    `json:"client_cert,omitempty"`
+	ClientKey  string    `json:"-"`
+	HasTLS     bool      `json:"has_tls"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type EndpointStore struct {
+	db  *DB
+	box *SecretBox
+}
+
+func NewEndpointStore(db *DB, box *SecretBox) *EndpointStore {
+	return &EndpointStore{db: db, box: box}
+}
+
+func (es *EndpointStore) Set(endpoint *DockerEndpoint) error {
+	var clientKey []byte
+	if endpoint.ClientKey != "" {
+		encrypted, err := es.box.Encrypt([]byte(endpoint.ClientKey))
+		if err != nil {
+			return err
+		}
+		clientKey = encrypted
+	}
+
+	query := `
+		INSERT INTO docker_endpoints (name, url, tls_verify, ca_cert, client_cert, client_key)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			url = excluded.url,
+			tls_verify = excluded.tls_verify,
+			ca_cert = excluded.ca_cert,
+			client_cert = excluded.client_cert,
+			client_key = excluded.client_key
+	`
+
+	_, err := es.db.conn.Exec(query,
+		endpoint.Name,
+		endpoint.URL,
+		endpoint.TLSVerify,
+		endpoint.CACert,
+		endpoint.ClientCert,
+		clientKey,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set Docker endpoint: %w", err)
+	}
+	return nil
+}
+
+// Get returns an endpoint with its client key decrypted.
+func (es *EndpointStore) Get(name string) (*DockerEndpoint, error) {
+	query := `
+		SELECT id, name, url, tls_verify, ca_cert, client_cert, client_key, created_at, updated_at
+		FROM docker_endpoints
+		WHERE name = ?
+	`
+
+	var endpoint DockerEndpoint
+	var clientKey []byte
+	err := es.db.conn.QueryRow(query, name).Scan(
+		&endpoint.ID,
+		&endpoint.Name,
+		&endpoint.URL,
+		&endpoint.TLSVerify,
+		&endpoint.CACert,
+		&endpoint.ClientCert,
+		&clientKey,
+		&endpoint.CreatedAt,
+		&endpoint.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("Docker endpoint not found: %s", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Docker endpoint: %w", err)
+	}
+
+	if len(clientKey) > 0 {
+		key, err := es.box.Decrypt(clientKey)
+		if err != nil {
+			return nil, err
+		}
+		endpoint.ClientKey = string(key)
+	}
+	endpoint.HasTLS = endpoint.CACert != "" || endpoint.ClientCert != ""
+
+	return &endpoint, nil
+}
+
+// List returns all endpoints without their certificates and keys.
+func (es *EndpointStore) List() ([]DockerEndpoint, error) {
+	query := `
+		SELECT id, name, url, tls_verify, ca_cert != '' OR client_cert != '', created_at, updated_at
+		FROM docker_endpoints
+		ORDER BY name
+	`
+
+	rows, err := es.db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Docker endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var endpoints []DockerEndpoint
+	for rows.Next() {
+		var endpoint DockerEndpoint
+		err := rows.Scan(
+			&endpoint.ID,
+			&endpoint.Name,
+			&endpoint.URL,
+			&endpoint.TLSVerify,
+			&endpoint.HasTLS,
+			&endpoint.CreatedAt,
+			&endpoint.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan Docker endpoint: %w", err)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, rows.Err()
+}
+
+func (es *EndpointStore) Delete(name string) error {
+	query := `DELETE FROM docker_endpoints WHERE name = ?`
+
+	result, err := es.db.conn.Exec(query, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete Docker endpoint: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("Docker endpoint not found: %s", name)
+	}
+
+	return nil
+}
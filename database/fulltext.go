@@ -0,0 +1,70 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: FTS5 indexes shared by the log archive and chat history
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// initFullText adds an FTS5 index named ftsTable over one column of table,
+// kept in sync by triggers, and reports whether it is usable. Without FTS5
+// the triggers are dropped so writes keep working, and the index is rebuilt
+// once a build with FTS5 installs them again.
+func initFullText(conn *sql.DB, table, ftsTable, column string) (bool, error) {
+	var synced int
+	err := conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?`, ftsTable+"_insert").Scan(&synced)
+	if err != nil {
+		return false, err
+	}
+
+	// The probe catches an index left behind by an earlier build with FTS5,
+	// which CREATE ... IF NOT EXISTS does not load
+	_, err = conn.Exec(fmt.Sprintf(`CREATE VIRTUAL TABLE IF NOT EXISTS %[2]s USING fts5(%[3]s, content='%[1]s', content_rowid='id')`, table, ftsTable, column))
+	if err == nil {
+		_, err = conn.Exec(fmt.Sprintf(`SELECT rowid FROM %s LIMIT 0`, ftsTable))
+	}
+	if err != nil {
+		log.Printf("Full-text search over %s unavailable, using substring matching: %v", table, err)
+		_, err := conn.Exec(fmt.Sprintf(`
+			DROP TRIGGER IF EXISTS %[1]s_insert;
+			DROP TRIGGER IF EXISTS %[1]s_delete;
+			DROP TRIGGER IF EXISTS %[1]s_update;
+		`, ftsTable))
+		return false, err
+	}
+
+	triggers := fmt.Sprintf(`
+	CREATE TRIGGER IF NOT EXISTS %[2]s_insert
+	AFTER INSERT ON %[1]s
+	BEGIN
+		INSERT INTO %[2]s(rowid, %[3]s) VALUES (NEW.id, NEW.%[3]s);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS %[2]s_delete
+	AFTER DELETE ON %[1]s
+	BEGIN
+		INSERT INTO %[2]s(%[2]s, rowid, %[3]s) VALUES ('delete', OLD.id, OLD.%[3]s);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS %[2]s_update
+	AFTER UPDATE OF %[3]s ON %[1]s
+	BEGIN
+		INSERT INTO %[2]s(%[2]s, rowid, %[3]s) VALUES ('delete', OLD.id, OLD.%[3]s);
+		INSERT INTO %[2]s(rowid, %[3]s) VALUES (NEW.id, NEW.%[3]s);
+	END;
+	`, table, ftsTable, column)
+	if _, err := conn.Exec(triggers); err != nil {
+		return false, err
+	}
+
+	if synced == 0 {
+		if _, err := conn.Exec(fmt.Sprintf(`INSERT INTO %[1]s(%[1]s) VALUES ('rebuild')`, ftsTable)); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
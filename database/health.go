@@ -0,0 +1,223 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Container health policy and action storage
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type HealthPolicy struct {
+	ID                 int       `json:"id"`
+	ContainerName      string    `json:"container_name"`
+	Enabled            bool      `json:"enabled"`
+	UnhealthyThreshold int       `json:"unhealthy_threshold"`
+	MaxRestartsPerHour int       `json:"max_restarts_per_hour"`
+	BackoffSeconds     int       `json:"backoff_seconds"`
+	MaxBackoffSeconds  int       `json:"max_backoff_seconds"`
+	RestartOnExit      bool      `json:"restart_on_exit"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type HealthAction struct {
+	ID            int       `json:"id"`
+	ContainerName string    `json:"container_name"`
+	ContainerID   string    `json:"container_id,omitempty"`
+	Action        string    `json:"action"`
+	Reason        string    `json:"reason"`
+	Success       bool      `json:"success"`
+	Error         string    `json:"error,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type HealthStore struct {
+	db *DB
+}
+
+func NewHealthStore(db *DB) *HealthStore {
+	return &HealthStore{db: db}
+}
+
+func (hs *HealthStore) SetPolicy(policy *HealthPolicy) error {
+	query := `
+		INSERT INTO health_policies (container_name, enabled, unhealthy_threshold, max_restarts_per_hour, backoff_seconds, max_backoff_seconds, restart_on_exit)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(container_name) DO UPDATE SET
+			enabled = excluded.enabled,
+			unhealthy_threshold = excluded.unhealthy_threshold,
+			max_restarts_per_hour = excluded.max_restarts_per_hour,
+			backoff_seconds = excluded.backoff_seconds,
+			max_backoff_seconds = excluded.max_backoff_seconds,
+			restart_on_exit = excluded.restart_on_exit
+	`
+
+	_, err := hs.db.conn.Exec(query,
+		policy.ContainerName,
+		policy.Enabled,
+		policy.UnhealthyThreshold,
+		policy.MaxRestartsPerHour,
+		policy.BackoffSeconds,
+		policy.MaxBackoffSeconds,
+		policy.RestartOnExit,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set health policy: %w", err)
+	}
+	return nil
+}
+
+func (hs *HealthStore) GetPolicy(containerName string) (*HealthPolicy, error) {
+	query := `
+		SELECT id, container_name, enabled, unhealthy_threshold, max_restarts_per_hour, backoff_seconds, max_backoff_seconds, restart_on_exit, created_at, updated_at
+		FROM health_policies
+		WHERE container_name = ?
+	`
+
+	policy, err := scanHealthPolicy(hs.db.conn.QueryRow(query, containerName))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("health policy not found: %s", containerName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+func (hs *HealthStore) ListPolicies() ([]HealthPolicy, error) {
+	query := `
+		SELECT id, container_name, enabled, unhealthy_threshold, max_restarts_per_hour, backoff_seconds, max_backoff_seconds, restart_on_exit, created_at, updated_at
+		FROM health_policies
+		ORDER BY container_name
+	`
+
+	rows, err := hs.db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list health policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []HealthPolicy
+	for rows.Next() {
+		policy, err := scanHealthPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan health policy: %w", err)
+		}
+		policies = append(policies, *policy)
+	}
+
+	return policies, rows.Err()
+}
+
+func (hs *HealthStore) DeletePolicy(containerName string) error {
+	query := `DELETE FROM health_policies WHERE container_name = ?`
+
+	result, err := hs.db.conn.Exec(query, containerName)
+	if err != nil {
+		return fmt.Errorf("failed to delete health policy: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("health policy not found: %s", containerName)
+	}
+
+	return nil
+}
+
+func (hs *HealthStore) RecordAction(action *HealthAction) error {
+	query := `
+		INSERT INTO health_actions (container_name, container_id, action, reason, success, error)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := hs.db.conn.Exec(query,
+		action.ContainerName,
+		action.ContainerID,
+		action.Action,
+		action.Reason,
+		action.Success,
+		action.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record health action: %w", err)
+	}
+
+	id, _ := result.LastInsertId()
+	action.ID = int(id)
+	action.CreatedAt = time.Now()
+
+	return nil
+}
+
+func (hs *HealthStore) ListActions(containerName string, limit int) ([]HealthAction, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `
+		SELECT id, container_name, container_id, action, reason, success, error, created_at
+		FROM health_actions
+		WHERE (? = '' OR container_name = ?)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := hs.db.conn.Query(query, containerName, containerName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list health actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []HealthAction
+	for rows.Next() {
+		var action HealthAction
+		err := rows.Scan(
+			&action.ID,
+			&action.ContainerName,
+			&action.ContainerID,
+			&action.Action,
+			&action.Reason,
+			&action.Success,
+			&action.Error,
+			&action.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan health action: %w", err)
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanHealthPolicy(row rowScanner) (*HealthPolicy, error) {
+	var policy HealthPolicy
+	err := row.Scan(
+		&policy.ID,
+		&policy.ContainerName,
+		&policy.Enabled,
+		&policy.UnhealthyThreshold,
+		&policy.MaxRestartsPerHour,
+		&policy.BackoffSeconds,
+		&policy.MaxBackoffSeconds,
+		&policy.RestartOnExit,
+		&policy.CreatedAt,
+		&policy.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
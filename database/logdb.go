@@ -0,0 +1,113 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Container log archive database connection and initialization
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	defaultLogDBPath = "/var/lib/bnhelper/logs.db"
+)
+
+// LogDB holds archived container logs. It is kept apart from the
+// configuration database so log churn and retention never touch settings.
+type LogDB struct {
+	conn *sql.DB
+	// fullText is false when SQLite was built without FTS5, in which case
+	// searches fall back to substring matching.
+	fullText bool
+}
+
+func NewLogDB(dbPath string) (*LogDB, error) {
+	if dbPath == "" {
+		dbPath = defaultLogDBPath
+	}
+
+	dbDir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dbDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log database directory: %w", err)
+	}
+
+	conn, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log database: %w", err)
+	}
+
+	conn.SetMaxOpenConns(1)
+
+	db := &LogDB{conn: conn}
+
+	if err := db.initialize(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to initialize log database: %w", err)
+	}
+
+	log.Printf("Log database initialized at: %s", dbPath)
+	return db, nil
+}
+
+func (db *LogDB) initialize() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS log_targets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container_name TEXT NOT NULL UNIQUE,
+		enabled INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS container_logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		container_name TEXT NOT NULL,
+		container_id TEXT NOT NULL,
+		stream TEXT NOT NULL,
+		ts INTEGER NOT NULL,
+		line TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_container_logs_name_ts ON container_logs(container_name, ts);
+	CREATE INDEX IF NOT EXISTS idx_container_logs_ts ON container_logs(ts);
+
+	CREATE TRIGGER IF NOT EXISTS update_log_targets_timestamp 
+	AFTER UPDATE ON log_targets
+	FOR EACH ROW
+	BEGIN
+		UPDATE log_targets SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
+	END;
+	`
+
+	if _, err := db.conn.Exec(schema); err != nil {
+		return err
+	}
+
+	fullText, err := initFullText(db.conn, "container_logs", "container_logs_fts", "line")
+	if err != nil {
+		return err
+	}
+	db.fullText = fullText
+	return nil
+}
+
+// FullText reports whether searches use the FTS5 index.
+func (db *LogDB) FullText() bool {
+	return db.fullText
+}
+
+func (db *LogDB) Close() error {
+	if db.conn != nil {
+		return db.conn.Close()
+	}
+	return nil
+}
+
+func (db *LogDB) GetConnection() *sql.DB {
+	return db.conn
+}
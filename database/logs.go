@@ -0,0 +1,374 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Archived container log storage and search
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLogSearchLimit = 100
+	MaxLogSearchLimit     = 1000
+
+	// pruneTarget is the share of the size limit the archive is trimmed to,
+	// so pruning does not run again as soon as a few lines arrive.
+	pruneTarget = 0.9
+	// maxPrunePasses bounds a prune, as freed pages only show up in the
+	// database size once SQLite has merged the full-text index.
+	maxPrunePasses = 5
+)
+
+// LogTarget is a container whose logs are archived. Targets are matched by
+// name so the archive carries on when a container is recreated.
+type LogTarget struct {
+	ID            int       `json:"id"`
+	ContainerName string    `json:"container_name"`
+	Enabled       bool      `json:"enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type LogEntry struct {
+	ID            int64     `json:"id"`
+	ContainerName string    `json:"container_name"`
+	ContainerID   string    `json:"container_id"`
+	Stream        string    `json:"stream"`
+	Time          time.Time `json:"time"`
+	Line          string    `json:"line"`
+}
+
+// LogQuery selects archived lines. Text matches lines containing all of its
+// words; empty fields do not filter.
+type LogQuery struct {
+	Text       string
+	Containers []string
+	Stream     string
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+type LogUsage struct {
+	Bytes    int64 `json:"bytes"`
+	Lines    int64 `json:"lines"`
+	FullText bool  `json:"full_text"`
+}
+
+type LogStore struct {
+	db *LogDB
+}
+
+func NewLogStore(db *LogDB) *LogStore {
+	return &LogStore{db: db}
+}
+
+func (ls *LogStore) SetTarget(containerName string, enabled bool) (*LogTarget, error) {
+	query := `
+		INSERT INTO log_targets (container_name, enabled)
+		VALUES (?, ?)
+		ON CONFLICT(container_name) DO UPDATE SET enabled = excluded.enabled
+	`
+
+	if _, err := ls.db.conn.Exec(query, containerName, enabled); err != nil {
+		return nil, fmt.Errorf("failed to set log target: %w", err)
+	}
+
+	return ls.GetTarget(containerName)
+}
+
+func (ls *LogStore) GetTarget(containerName string) (*LogTarget, error) {
+	query := `
+		SELECT id, container_name, enabled, created_at, updated_at
+		FROM log_targets
+		WHERE container_name = ?
+	`
+
+	target, err := scanLogTarget(ls.db.conn.QueryRow(query, containerName))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("log target not found: %s", containerName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log target: %w", err)
+	}
+
+	return target, nil
+}
+
+func (ls *LogStore) ListTargets() ([]LogTarget, error) {
+	query := `
+		SELECT id, container_name, enabled, created_at, updated_at
+		FROM log_targets
+		ORDER BY container_name
+	`
+
+	rows, err := ls.db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log targets: %w", err)
+	}
+	defer rows.Close()
+
+	targets := []LogTarget{}
+	for rows.Next() {
+		target, err := scanLogTarget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan log target: %w", err)
+		}
+		targets = append(targets, *target)
+	}
+
+	return targets, rows.Err()
+}
+
+// DeleteTarget stops archiving a container. Its archived lines are kept
+// unless purge is set.
+func (ls *LogStore) DeleteTarget(containerName string, purge bool) error {
+	result, err := ls.db.conn.Exec(`DELETE FROM log_targets WHERE container_name = ?`, containerName)
+	if err != nil {
+		return fmt.Errorf("failed to delete log target: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("log target not found: %s", containerName)
+	}
+
+	if purge {
+		if _, err := ls.db.conn.Exec(`DELETE FROM container_logs WHERE container_name = ?`, containerName); err != nil {
+			return fmt.Errorf("failed to purge container logs: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Append stores a batch of lines in one transaction.
+func (ls *LogStore) Append(entries []LogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := ls.db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO container_logs (container_name, container_id, stream, ts, line)
+		VALUES (?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare log insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		if _, err := stmt.Exec(entry.ContainerName, entry.ContainerID, entry.Stream, entry.Time.UnixNano(), entry.Line); err != nil {
+			return fmt.Errorf("failed to store log line: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit log lines: %w", err)
+	}
+	return nil
+}
+
+// LastTime returns the timestamp of the newest archived line of a container,
+// or the zero time when nothing is archived yet.
+func (ls *LogStore) LastTime(containerName string) (time.Time, error) {
+	var ts sql.NullInt64
+	err := ls.db.conn.QueryRow(`SELECT MAX(ts) FROM container_logs WHERE container_name = ?`, containerName).Scan(&ts)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last log time: %w", err)
+	}
+	if !ts.Valid {
+		return time.Time{}, nil
+	}
+	return time.Unix(0, ts.Int64), nil
+}
+
+// Search returns matching lines, newest first.
+func (ls *LogStore) Search(q LogQuery) ([]LogEntry, error) {
+	var conditions []string
+	var args []interface{}
+
+	if words := strings.Fields(q.Text); len(words) > 0 {
+		if ls.db.fullText {
+			conditions = append(conditions, `id IN (SELECT rowid FROM container_logs_fts WHERE container_logs_fts MATCH ?)`)
+			args = append(args, ftsQuery(words))
+		} else {
+			for _, word := range words {
+				conditions = append(conditions, `line LIKE ? ESCAPE '\'`)
+				args = append(args, "%"+likeEscaper.Replace(word)+"%")
+			}
+		}
+	}
+	if len(q.Containers) > 0 {
+		conditions = append(conditions, `container_name IN (?`+strings.Repeat(`, ?`, len(q.Containers)-1)+`)`)
+		for _, name := range q.Containers {
+			args = append(args, name)
+		}
+	}
+	if q.Stream != "" {
+		conditions = append(conditions, `stream = ?`)
+		args = append(args, q.Stream)
+	}
+	if !q.From.IsZero() {
+		conditions = append(conditions, `ts >= ?`)
+		args = append(args, q.From.UnixNano())
+	}
+	if !q.To.IsZero() {
+		conditions = append(conditions, `ts <= ?`)
+		args = append(args, q.To.UnixNano())
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLogSearchLimit
+	}
+	if limit > MaxLogSearchLimit {
+		limit = MaxLogSearchLimit
+	}
+
+	query := `SELECT id, container_name, container_id, stream, ts, line FROM container_logs`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, ` AND `)
+	}
+	query += ` ORDER BY ts DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := ls.db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search logs: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []LogEntry{}
+	for rows.Next() {
+		var entry LogEntry
+		var ts int64
+		if err := rows.Scan(&entry.ID, &entry.ContainerName, &entry.ContainerID, &entry.Stream, &ts, &entry.Line); err != nil {
+			return nil, fmt.Errorf("failed to scan log line: %w", err)
+		}
+		entry.Time = time.Unix(0, ts).UTC()
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Usage reports the space taken by the archive, including the search index.
+func (ls *LogStore) Usage() (*LogUsage, error) {
+	usage := &LogUsage{FullText: ls.db.fullText}
+
+	bytes, err := ls.usedBytes()
+	if err != nil {
+		return nil, err
+	}
+	usage.Bytes = bytes
+
+	if err := ls.db.conn.QueryRow(`SELECT COUNT(*) FROM container_logs`).Scan(&usage.Lines); err != nil {
+		return nil, fmt.Errorf("failed to count log lines: %w", err)
+	}
+
+	return usage, nil
+}
+
+// Prune deletes the oldest lines across all containers until the archive fits
+// in maxBytes and returns how many lines were removed.
+func (ls *LogStore) Prune(maxBytes int64) (int64, error) {
+	var removed int64
+	for pass := 0; pass < maxPrunePasses; pass++ {
+		used, err := ls.usedBytes()
+		if err != nil {
+			return removed, err
+		}
+		if used <= maxBytes {
+			break
+		}
+
+		var lines int64
+		if err := ls.db.conn.QueryRow(`SELECT COUNT(*) FROM container_logs`).Scan(&lines); err != nil {
+			return removed, fmt.Errorf("failed to count log lines: %w", err)
+		}
+		if lines == 0 {
+			break
+		}
+
+		// Assume lines are of similar size and remove the oldest share
+		excess := float64(used) - float64(maxBytes)*pruneTarget
+		batch := int64(float64(lines)*excess/float64(used)) + 1
+
+		result, err := ls.db.conn.Exec(`
+			DELETE FROM container_logs WHERE id IN (
+				SELECT id FROM container_logs ORDER BY ts, id LIMIT ?
+			)
+		`, batch)
+		if err != nil {
+			return removed, fmt.Errorf("failed to prune logs: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		removed += n
+
+		// Deletes only add tombstones to the index until it is merged
+		if ls.db.fullText {
+			if _, err := ls.db.conn.Exec(`INSERT INTO container_logs_fts(container_logs_fts) VALUES ('optimize')`); err != nil {
+				return removed, fmt.Errorf("failed to optimize log index: %w", err)
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// usedBytes is the size of the database file minus its free pages, which
+// SQLite reuses for new lines.
+func (ls *LogStore) usedBytes() (int64, error) {
+	var pages, free, pageSize int64
+	if err := ls.db.conn.QueryRow(`PRAGMA page_count`).Scan(&pages); err != nil {
+		return 0, fmt.Errorf("failed to get log database size: %w", err)
+	}
+	if err := ls.db.conn.QueryRow(`PRAGMA freelist_count`).Scan(&free); err != nil {
+		return 0, fmt.Errorf("failed to get log database size: %w", err)
+	}
+	if err := ls.db.conn.QueryRow(`PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("failed to get log database size: %w", err)
+	}
+	return (pages - free) * pageSize, nil
+}
+
+// ftsQuery quotes each word so characters such as - or : are searched for
+// rather than read as FTS5 operators.
+func ftsQuery(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func scanLogTarget(row rowScanner) (*LogTarget, error) {
+	var target LogTarget
+	err := row.Scan(
+		&target.ID,
+		&target.ContainerName,
+		&target.Enabled,
+		&target.CreatedAt,
+		&target.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
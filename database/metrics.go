@@ -0,0 +1,226 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Downsampled container metrics history
+
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// MetricsTier is one resolution of the metrics history and how long it is
+// kept.
+type MetricsTier struct {
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+// DefaultMetricsTiers keeps minute buckets for a day, 15 minute buckets for a
+// month and hourly buckets for a year. Tiers are ordered from finest to
+// coarsest.
+var DefaultMetricsTiers = []MetricsTier{
+	{Resolution: time.Minute, Retention: 24 * time.Hour},
+	{Resolution: 15 * time.Minute, Retention: 30 * 24 * time.Hour},
+	{Resolution: time.Hour, Retention: 365 * 24 * time.Hour},
+}
+
+// MetricsSample is one measurement of a container. Network and block I/O are
+// the bytes transferred since the previous sample.
+type MetricsSample struct {
+	ContainerName   string
+	Time            time.Time
+	CPUPercent      float64
+	MemoryBytes     int64
+	MemoryLimit     int64
+	NetRxBytes      int64
+	NetTxBytes      int64
+	BlockReadBytes  int64
+	BlockWriteBytes int64
+}
+
+// MetricsPoint is one bucket of the history. CPU and memory are averaged over
+// the samples in the bucket, with the peak alongside; I/O values are totals
+// for the bucket.
+type MetricsPoint struct {
+	Time            time.Time `json:"time"`
+	Samples         int       `json:"samples"`
+	CPUPercent      float64   `json:"cpu_percent"`
+	CPUMax          float64   `json:"cpu_max"`
+	MemoryBytes     int64     `json:"memory_bytes"`
+	MemoryMax       int64     `json:"memory_max"`
+	MemoryLimit     int64     `json:"memory_limit"`
+	NetRxBytes      int64     `json:"net_rx_bytes"`
+	NetTxBytes      int64     `json:"net_tx_bytes"`
+	BlockReadBytes  int64     `json:"block_read_bytes"`
+	BlockWriteBytes int64     `json:"block_write_bytes"`
+}
+
+type MetricsStore struct {
+	db    *DB
+	tiers []MetricsTier
+}
+
+func NewMetricsStore(db *DB, tiers []MetricsTier) *MetricsStore {
+	if len(tiers) == 0 {
+		tiers = DefaultMetricsTiers
+	}
+	return &MetricsStore{db: db, tiers: tiers}
+}
+
+func (ms *MetricsStore) Tiers() []MetricsTier {
+	return ms.tiers
+}
+
+// Record folds a sample into the current bucket of every tier, so the coarse
+// tiers never have to be recomputed from the fine ones.
+func (ms *MetricsStore) Record(sample MetricsSample) error {
+	query := `
+		INSERT INTO container_metrics (container_name, resolution, bucket, samples, cpu_percent, cpu_max, memory_bytes, memory_max, memory_limit, net_rx_bytes, net_tx_bytes, block_read_bytes, block_write_bytes)
+		VALUES (?, ?, ?, 1, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(container_name, resolution, bucket) DO UPDATE SET
+			samples = samples + 1,
+			cpu_percent = (cpu_percent * samples + excluded.cpu_percent) / (samples + 1),
+			cpu_max = MAX(cpu_max, excluded.cpu_max),
+			memory_bytes = (memory_bytes * samples + excluded.memory_bytes) / (samples + 1),
+			memory_max = MAX(memory_max, excluded.memory_max),
+			memory_limit = excluded.memory_limit,
+			net_rx_bytes = net_rx_bytes + excluded.net_rx_bytes,
+			net_tx_bytes = net_tx_bytes + excluded.net_tx_bytes,
+			block_read_bytes = block_read_bytes + excluded.block_read_bytes,
+			block_write_bytes = block_write_bytes + excluded.block_write_bytes
+	`
+
+	tx, err := ms.db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, tier := range ms.tiers {
+		resolution := int64(tier.Resolution / time.Second)
+		bucket := sample.Time.Unix() / resolution * resolution
+
+		_, err := tx.Exec(query,
+			sample.ContainerName,
+			resolution,
+			bucket,
+			sample.CPUPercent,
+			sample.CPUPercent,
+			sample.MemoryBytes,
+			sample.MemoryBytes,
+			sample.MemoryLimit,
+			sample.NetRxBytes,
+			sample.NetTxBytes,
+			sample.BlockReadBytes,
+			sample.BlockWriteBytes,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record metrics: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit metrics: %w", err)
+	}
+	return nil
+}
+
+// Query returns the buckets of one resolution between from and to, oldest
+// first.
+func (ms *MetricsStore) Query(containerName string, resolution time.Duration, from, to time.Time) ([]MetricsPoint, error) {
+	query := `
+		SELECT bucket, samples, cpu_percent, cpu_max, memory_bytes, memory_max, memory_limit, net_rx_bytes, net_tx_bytes, block_read_bytes, block_write_bytes
+		FROM container_metrics
+		WHERE container_name = ? AND resolution = ? AND bucket >= ? AND bucket <= ?
+		ORDER BY bucket
+	`
+
+	seconds := int64(resolution / time.Second)
+	rows, err := ms.db.conn.Query(query, containerName, seconds, from.Unix()/seconds*seconds, to.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	points := []MetricsPoint{}
+	for rows.Next() {
+		var point MetricsPoint
+		var bucket int64
+		err := rows.Scan(
+			&bucket,
+			&point.Samples,
+			&point.CPUPercent,
+			&point.CPUMax,
+			&point.MemoryBytes,
+			&point.MemoryMax,
+			&point.MemoryLimit,
+			&point.NetRxBytes,
+			&point.NetTxBytes,
+			&point.BlockReadBytes,
+			&point.BlockWriteBytes,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan metrics: %w", err)
+		}
+		point.Time = time.Unix(bucket, 0).UTC()
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+// ResolutionFor picks the finest tier that still holds data back to from.
+func (ms *MetricsStore) ResolutionFor(from time.Time) time.Duration {
+	age := time.Since(from)
+	for _, tier := range ms.tiers {
+		if age <= tier.Retention {
+			return tier.Resolution
+		}
+	}
+	return ms.tiers[len(ms.tiers)-1].Resolution
+}
+
+// HasResolution reports whether resolution is one of the configured tiers.
+func (ms *MetricsStore) HasResolution(resolution time.Duration) bool {
+	for _, tier := range ms.tiers {
+		if tier.Resolution == resolution {
+			return true
+		}
+	}
+	return false
+}
+
+// Prune deletes buckets that have aged out of their tier and returns how many
+// were removed.
+func (ms *MetricsStore) Prune(now time.Time) (int64, error) {
+	var removed int64
+	for _, tier := range ms.tiers {
+		result, err := ms.db.conn.Exec(`DELETE FROM container_metrics WHERE resolution = ? AND bucket < ?`,
+			int64(tier.Resolution/time.Second), now.Add(-tier.Retention).Unix())
+		if err != nil {
+			return removed, fmt.Errorf("failed to prune metrics: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		removed += n
+	}
+	return removed, nil
+}
+
+// ListContainers returns the names of containers with recorded metrics.
+func (ms *MetricsStore) ListContainers() ([]string, error) {
+	rows, err := ms.db.conn.Query(`SELECT DISTINCT container_name FROM container_metrics ORDER BY container_name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list metrics containers: %w", err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan metrics container: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
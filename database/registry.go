@@ -0,0 +1,141 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Encrypted container registry credential storage
+
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCredentialNotFound is returned by Get when no credential is stored for
+// a registry.
+var ErrCredentialNotFound = errors.New("registry credential not found")
+
+type RegistryCredential struct {
+	ID        int       `json:"id"`
+	Registry  string    `json:"registry"`
+	Username  string    `json:"username"`
+	Password  string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type RegistryStore struct {
+	db  *DB
+	box *SecretBox
+}
+
+func NewRegistryStore(db *DB, box *SecretBox) *RegistryStore {
+	return &RegistryStore{db: db, box: box}
+}
+
+func (rs *RegistryStore) Set(registry, username, password string) error {
+	secret, err := rs.box.Encrypt([]byte(password))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO registry_credentials (registry, username, secret)
+		VALUES (?, ?, ?)
+		ON CONFLICT(registry) DO UPDATE SET
+			username = excluded.username,
+			secret = excluded.secret
+	`
+
+	if _, err := rs.db.conn.Exec(query, registry, username, secret); err != nil {
+		return fmt.Errorf("failed to set registry credential: %w", err)
+	}
+	return nil
+}
+
+// Get returns the decrypted credential for a registry host.
+func (rs *RegistryStore) Get(registry string) (*RegistryCredential, error) {
+	query := `
+		SELECT id, registry, username, secret, created_at, updated_at
+		FROM registry_credentials
+		WHERE registry = ?
+	`
+
+	var cred RegistryCredential
+	var secret []byte
+	err := rs.db.conn.QueryRow(query, registry).Scan(
+		&cred.ID,
+		&cred.Registry,
+		&cred.Username,
+		&secret,
+		&cred.CreatedAt,
+		&cred.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrCredentialNotFound, registry)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registry credential: %w", err)
+	}
+
+	password, err := rs.box.Decrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+	cred.Password = string(password)
+
+	return &cred, nil
+}
+
+// List returns all stored credentials without their passwords.
+func (rs *RegistryStore) List() ([]RegistryCredential, error) {
+	query := `
+		SELECT id, registry, username, created_at, updated_at
+		FROM registry_credentials
+		ORDER BY registry
+	`
+
+	rows, err := rs.db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list registry credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var creds []RegistryCredential
+	for rows.Next() {
+		var cred RegistryCredential
+		err := rows.Scan(
+			&cred.ID,
+			&cred.Registry,
+			&cred.Username,
+			&cred.CreatedAt,
+			&cred.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan registry credential: %w", err)
+		}
+		creds = append(creds, cred)
+	}
+
+	return creds, rows.Err()
+}
+
+func (rs *RegistryStore) Delete(registry string) error {
+	query := `DELETE FROM registry_credentials WHERE registry = ?`
+
+	result, err := rs.db.conn.Exec(query, registry)
+	if err != nil {
+		return fmt.Errorf("failed to delete registry credential: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("registry credential not found: %s", registry)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Encryption of secrets stored in the database
+
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultSecretKeyPath = defaultDBDir + "/secret.key"
+	secretKeySize        = 32
+)
+
+// SecretBox encrypts values with AES-256-GCM using a key kept in a file next
+// to the database, so a copied database alone does not reveal the secrets.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+func NewSecretBox(keyPath string) (*SecretBox, error) {
+	if keyPath == "" {
+		keyPath = defaultSecretKeyPath
+	}
+
+	key, err := loadOrCreateKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &SecretBox{aead: aead}, nil
+}
+
+func (sb *SecretBox) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, sb.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return sb.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (sb *SecretBox) Decrypt(ciphertext []byte) ([]byte, error) {
+	nonceSize := sb.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	plaintext, err := sb.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func loadOrCreateKey(keyPath string) ([]byte, error) {
+	key, err := os.ReadFile(keyPath)
+	if err == nil {
+		if len(key) != secretKeySize {
+			return nil, fmt.Errorf("invalid secret key size in %s", keyPath)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read secret key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create secret key directory: %w", err)
+	}
+
+	key = make([]byte, secretKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate secret key: %w", err)
+	}
+
+	if err := os.WriteFile(keyPath, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write secret key: %w", err)
+	}
+
+	return key, nil
+}
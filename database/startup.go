@@ -0,0 +1,197 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Container startup group and dependency storage
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type StartupEntry struct {
+	ID             int       `json:"id"`
+	ContainerName  string    `json:"container_name"`
+	Group          int       `json:"group"`
+	DependsOn      []string  `json:"depends_on"`
+	WaitHealthy    bool      `json:"wait_healthy"`
+	TimeoutSeconds int       `json:"timeout_seconds"`
+	Enabled        bool      `json:"enabled"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type StartupStore struct {
+	db *DB
+}
+
+func NewStartupStore(db *DB) *StartupStore {
+	return &StartupStore{db: db}
+}
+
+// Set creates or replaces an entry together with its full dependency list.
+func (ss *StartupStore) Set(entry *StartupEntry) error {
+	tx, err := ss.db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO startup_entries (container_name, start_group, wait_healthy, timeout_seconds, enabled)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(container_name) DO UPDATE SET
+			start_group = excluded.start_group,
+			wait_healthy = excluded.wait_healthy,
+			timeout_seconds = excluded.timeout_seconds,
+			enabled = excluded.enabled
+	`
+
+	_, err = tx.Exec(query,
+		entry.ContainerName,
+		entry.Group,
+		entry.WaitHealthy,
+		entry.TimeoutSeconds,
+		entry.Enabled,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set startup entry: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM startup_dependencies WHERE container_name = ?`, entry.ContainerName); err != nil {
+		return fmt.Errorf("failed to clear startup dependencies: %w", err)
+	}
+
+	for _, dep := range entry.DependsOn {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO startup_dependencies (container_name, depends_on) VALUES (?, ?)`,
+			entry.ContainerName, dep)
+		if err != nil {
+			return fmt.Errorf("failed to set startup dependency: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit startup entry: %w", err)
+	}
+	return nil
+}
+
+func (ss *StartupStore) Get(containerName string) (*StartupEntry, error) {
+	query := `
+		SELECT id, container_name, start_group, wait_healthy, timeout_seconds, enabled, created_at, updated_at
+		FROM startup_entries
+		WHERE container_name = ?
+	`
+
+	entry, err := scanStartupEntry(ss.db.conn.QueryRow(query, containerName))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("startup entry not found: %s", containerName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get startup entry: %w", err)
+	}
+
+	deps, err := ss.dependencies()
+	if err != nil {
+		return nil, err
+	}
+	entry.DependsOn = deps[entry.ContainerName]
+
+	return entry, nil
+}
+
+func (ss *StartupStore) List() ([]StartupEntry, error) {
+	query := `
+		SELECT id, container_name, start_group, wait_healthy, timeout_seconds, enabled, created_at, updated_at
+		FROM startup_entries
+		ORDER BY start_group, container_name
+	`
+
+	rows, err := ss.db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list startup entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []StartupEntry
+	for rows.Next() {
+		entry, err := scanStartupEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan startup entry: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	deps, err := ss.dependencies()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].DependsOn = deps[entries[i].ContainerName]
+	}
+
+	return entries, nil
+}
+
+func (ss *StartupStore) Delete(containerName string) error {
+	result, err := ss.db.conn.Exec(`DELETE FROM startup_entries WHERE container_name = ?`, containerName)
+	if err != nil {
+		return fmt.Errorf("failed to delete startup entry: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("startup entry not found: %s", containerName)
+	}
+
+	if _, err := ss.db.conn.Exec(`DELETE FROM startup_dependencies WHERE container_name = ?`, containerName); err != nil {
+		return fmt.Errorf("failed to delete startup dependencies: %w", err)
+	}
+
+	return nil
+}
+
+// dependencies returns every stored dependency keyed by dependent container.
+func (ss *StartupStore) dependencies() (map[string][]string, error) {
+	rows, err := ss.db.conn.Query(`SELECT container_name, depends_on FROM startup_dependencies ORDER BY container_name, depends_on`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list startup dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	deps := make(map[string][]string)
+	for rows.Next() {
+		var name, dep string
+		if err := rows.Scan(&name, &dep); err != nil {
+			return nil, fmt.Errorf("failed to scan startup dependency: %w", err)
+		}
+		deps[name] = append(deps[name], dep)
+	}
+
+	return deps, rows.Err()
+}
+
+func scanStartupEntry(row rowScanner) (*StartupEntry, error) {
+	var entry StartupEntry
+	err := row.Scan(
+		&entry.ID,
+		&entry.ContainerName,
+		&entry.Group,
+		&entry.WaitHealthy,
+		&entry.TimeoutSeconds,
+		&entry.Enabled,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
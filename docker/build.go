@@ -0,0 +1,106 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Image builds from a tar context
+
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type BuildOptions struct {
+	Tags       []string
+	Dockerfile string
+	BuildArgs  map[string]string
+	Target     string
+	NoCache    bool
+	Pull       bool
+	// AuthConfigs are credentials for pulling base images, keyed by registry.
+	AuthConfigs map[string]AuthConfig
+}
+
+type BuildResult struct {
+	ImageID string `json:"image_id,omitempty"`
+	// FailedStep is the last "Step N/M" line seen before an error.
+	FailedStep string `json:"failed_step,omitempty"`
+}
+
+// BuildImage posts a tar build context (optionally gzip-compressed) to /build.
+// Each progress message is passed to fn when it is non-nil.
+func (c *Client) BuildImage(ctx context.Context, buildContext io.Reader, opts BuildOptions, fn func(JSONMessage)) (*BuildResult, error) {
+	query := url.Values{}
+	for _, tag := range opts.Tags {
+		query.Add("t", tag)
+	}
+	if opts.Dockerfile != "" {
+		query.Set("dockerfile", opts.Dockerfile)
+	}
+	if opts.Target != "" {
+		query.Set("target", opts.Target)
+	}
+	if opts.NoCache {
+		query.Set("nocache", "1")
+	}
+	if opts.Pull {
+		query.Set("pull", "1")
+	}
+	if len(opts.BuildArgs) > 0 {
+		args, err := json.Marshal(opts.BuildArgs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode build args: %w", err)
+		}
+		query.Set("buildargs", string(args))
+	}
+	query.Set("rm", "1")
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/x-tar")
+	if len(opts.AuthConfigs) > 0 {
+		data, err := json.Marshal(opts.AuthConfigs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode registry config: %w", err)
+		}
+		headers.Set("X-Registry-Config", base64.URLEncoding.EncodeToString(data))
+	}
+
+	resp, err := c.doRequestWithHeaders(ctx, c.streamClient, http.MethodPost, "/build?"+query.Encode(), buildContext, headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("build image", resp)
+	}
+
+	result := &BuildResult{}
+	lastStep := ""
+	err = readJSONMessages(resp.Body, func(msg JSONMessage) {
+		if strings.HasPrefix(msg.Stream, "Step ") {
+			lastStep = strings.TrimSpace(msg.Stream)
+		}
+		if len(msg.Aux) > 0 {
+			var aux struct {
+				ID string `json:"ID"`
+			}
+			if json.Unmarshal(msg.Aux, &aux) == nil && aux.ID != "" {
+				result.ImageID = aux.ID
+			}
+		}
+		if fn != nil {
+			fn(msg)
+		}
+	})
+	if err != nil {
+		result.FailedStep = lastStep
+		return result, err
+	}
+
+	return result, nil
+}
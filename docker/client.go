@@ -4,12 +4,17 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 )
 
@@ -19,20 +24,133 @@ const (
 )
 
 type Client struct {
-	httpClient *http.Client
-	socketPath string
+	httpClient   *http.Client
+	streamClient *http.Client
+	baseURL      string
+	socketPath   string
+
+	versionMu  sync.Mutex
+	apiVersion string
+	engine     string
 }
 
 type Container struct {
-	ID      string   `json:"Id"`
-	Names   []string `json:"Names"`
-	Image   string   `json:"Image"`
-	ImageID string   `json:"ImageID"`
-	Command string   `json:"Command"`
-	Created int64    `json:"Created"`
-	State   string   `json:"State"`
-	Status  string   `json:"Status"`
-	Ports   []Port   `json:"Ports"`
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	ImageID string            `json:"ImageID"`
+	Command string            `json:"Command"`
+	Created int64             `json:"Created"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Ports   []Port            `json:"Ports"`
+	Labels  map[string]string `json:"Labels"`
+
+	NetworkSettings *SummaryNetworkSettings `json:"NetworkSettings,omitempty"`
+}
+
+type SummaryNetworkSettings struct {
+	Networks map[string]EndpointSettings `json:"Networks"`
+}
+
+type EndpointSettings struct {
+	NetworkID  string `json:"NetworkID"`
+	IPAddress  string `json:"IPAddress"`
+	Gateway    string `json:"Gateway"`
+	MacAddress string `json:"MacAddress"`
+}
+
+type ContainerJSON struct {
+	ID           string          `json:"Id"`
+	Name         string          `json:"Name"`
+	Image        string          `json:"Image"`
+	Created      string          `json:"Created"`
+	RestartCount int             `json:"RestartCount"`
+	State        ContainerState  `json:"State"`
+	Config       ContainerConfig `json:"Config"`
+	HostConfig   HostConfig      `json:"HostConfig"`
+	Mounts       []MountPoint    `json:"Mounts"`
+}
+
+type MountPoint struct {
+	Type        string `json:"Type"`
+	Name        string `json:"Name,omitempty"`
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+	Driver      string `json:"Driver,omitempty"`
+	Mode        string `json:"Mode"`
+	RW          bool   `json:"RW"`
+}
+
+type Volume struct {
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Mountpoint string            `json:"Mountpoint"`
+	CreatedAt  string            `json:"CreatedAt,omitempty"`
+	Labels     map[string]string `json:"Labels"`
+	Scope      string            `json:"Scope"`
+}
+
+type ContainerState struct {
+	Status     string  `json:"Status"`
+	Running    bool    `json:"Running"`
+	Paused     bool    `json:"Paused"`
+	Restarting bool    `json:"Restarting"`
+	OOMKilled  bool    `json:"OOMKilled"`
+	ExitCode   int     `json:"ExitCode"`
+	StartedAt  string  `json:"StartedAt"`
+	FinishedAt string  `json:"FinishedAt"`
+	Health     *Health `json:"Health,omitempty"`
+}
+
+type Health struct {
+	Status        string      `json:"Status"`
+	FailingStreak int         `json:"FailingStreak"`
+	Log           []HealthLog `json:"Log"`
+}
+
+type HealthLog struct {
+	Start    string `json:"Start"`
+	End      string `json:"End"`
+	ExitCode int    `json:"ExitCode"`
+	Output   string `json:"Output"`
+}
+
+type ContainerConfig struct {
+	Hostname string            `json:"Hostname"`
+	Image    string            `json:"Image"`
+	Env      []string          `json:"Env"`
+	Labels   map[string]string `json:"Labels"`
+	Tty      bool              `json:"Tty"`
+}
+
+type HostConfig struct {
+	Memory        int64         `json:"Memory"`
+	MemorySwap    int64         `json:"MemorySwap"`
+	CPUShares     int64         `json:"CpuShares"`
+	CPUPeriod     int64         `json:"CpuPeriod"`
+	CPUQuota      int64         `json:"CpuQuota"`
+	CpusetCpus    string        `json:"CpusetCpus"`
+	PidsLimit     *int64        `json:"PidsLimit"`
+	RestartPolicy RestartPolicy `json:"RestartPolicy"`
+}
+
+type RestartPolicy struct {
+	Name              string `json:"Name"`
+	MaximumRetryCount int    `json:"MaximumRetryCount"`
+}
+
+// UpdateConfig holds the resource limits accepted by /containers/{id}/update.
+// Zero values are omitted and leave the current setting unchanged.
+type UpdateConfig struct {
+	Memory        int64          `json:"Memory,omitempty"`
+	MemorySwap    int64          `json:"MemorySwap,omitempty"`
+	CPUShares     int64          `json:"CpuShares,omitempty"`
+	CPUPeriod     int64          `json:"CpuPeriod,omitempty"`
+	CPUQuota      int64          `json:"CpuQuota,omitempty"`
+	CpusetCpus    string         `json:"CpusetCpus,omitempty"`
+	PidsLimit     *int64         `json:"PidsLimit,omitempty"`
+	RestartPolicy *RestartPolicy `json:"RestartPolicy,omitempty"`
 }
 
 type Port struct {
@@ -43,13 +161,16 @@ type Port struct {
 }
 
 type Image struct {
-	ID          string   `json:"Id"`
-	ParentID    string   `json:"ParentId"`
-	RepoTags    []string `json:"RepoTags"`
-	RepoDigests []string `json:"RepoDigests"`
-	Created     int64    `json:"Created"`
-	Size        int64    `json:"Size"`
-	VirtualSize int64    `json:"VirtualSize"`
+	ID          string            `json:"Id"`
+	ParentID    string            `json:"ParentId"`
+	RepoTags    []string          `json:"RepoTags"`
+	RepoDigests []string          `json:"RepoDigests"`
+	Created     int64             `json:"Created"`
+	Size        int64             `json:"Size"`
+	VirtualSize int64             `json:"VirtualSize"`
+	SharedSize  int64             `json:"SharedSize"`
+	Labels      map[string]string `json:"Labels"`
+	Containers  int64             `json:"Containers"`
 }
 
 type Info struct {
@@ -69,6 +190,9 @@ type Info struct {
 	OSType            string `json:"OSType"`
 	Architecture      string `json:"Architecture"`
 	KernelVersion     string `json:"KernelVersion"`
+
+	// Engine is filled in by the helper: "docker" or "podman"
+	Engine string `json:"Engine,omitempty"`
 }
 
 type Version struct {
@@ -81,6 +205,14 @@ type Version struct {
 	Arch          string `json:"Arch"`
 	KernelVersion string `json:"KernelVersion"`
 	BuildTime     string `json:"BuildTime"`
+	Platform      struct {
+		Name string `json:"Name"`
+	} `json:"Platform"`
+	Components []Component `json:"Components,omitempty"`
+
+	// NegotiatedAPIVersion and Engine are filled in by the helper, not the daemon
+	NegotiatedAPIVersion string `json:"NegotiatedApiVersion,omitempty"`
+	Engine               string `json:"Engine,omitempty"`
 }
 
 func NewClient() *Client {
@@ -88,23 +220,117 @@ func NewClient() *Client {
 }
 
 func NewClientWithSocket(socketPath string) *Client {
+	var dialer net.Dialer
 	transport := &http.Transport{
 		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return net.Dial("unix", socketPath)
+			return dialer.DialContext(ctx, "unix", socketPath)
 		},
 	}
 
+	client := newClient(transport, "http://localhost")
+	client.socketPath = socketPath
+	return client
+}
+
+// NewClientWithEndpoint connects to a Docker endpoint given as unix:///path
+// or tcp://host:port. When tlsConfig is set, TCP connections use HTTPS.
+func NewClientWithEndpoint(endpoint string, tlsConfig *tls.Config) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Docker endpoint %q: %w", endpoint, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("invalid Docker endpoint %q: missing socket path", endpoint)
+		}
+		return NewClientWithSocket(u.Path), nil
+	case "tcp", "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid Docker endpoint %q: missing host", endpoint)
+		}
+
+		host := u.Host
+		if u.Port() == "" {
+			if tlsConfig != nil {
+				host = net.JoinHostPort(u.Hostname(), "2376")
+			} else {
+				host = net.JoinHostPort(u.Hostname(), "2375")
+			}
+		}
+
+		dialer := &net.Dialer{Timeout: DefaultTimeout, KeepAlive: 30 * time.Second}
+		transport := &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSClientConfig:     tlsConfig,
+			TLSHandshakeTimeout: 10 * time.Second,
+		}
+
+		scheme := "http"
+		if tlsConfig != nil || u.Scheme == "https" {
+			scheme = "https"
+		}
+		return newClient(transport, scheme+"://"+host), nil
+	default:
+		return nil, fmt.Errorf("unsupported Docker endpoint scheme: %s", u.Scheme)
+	}
+}
+
+// NewTLSConfig builds a client TLS configuration from PEM-encoded data. The
+// CA is optional and falls back to the system pool; the client certificate
+// and key must be given together.
+func NewTLSConfig(caPEM, certPEM, keyPEM []byte, verify bool) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: !verify,
+	}
+
+	if len(caPEM) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("failed to parse CA certificate")
+		}
+		config.RootCAs = pool
+	}
+
+	if len(certPEM) > 0 || len(keyPEM) > 0 {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+func newClient(transport *http.Transport, baseURL string) *Client {
 	return &Client{
 		httpClient: &http.Client{
 			Transport: transport,
 			Timeout:   DefaultTimeout,
 		},
-		socketPath: socketPath,
+		// Long-running operations such as pulls stream their progress and are
+		// bounded by the request context instead of a fixed timeout.
+		streamClient: &http.Client{
+			Transport: transport,
+		},
+		baseURL: baseURL,
 	}
 }
 
+// Close releases idle connections held by the client.
+func (c *Client) Close() {
+	c.httpClient.CloseIdleConnections()
+}
+
 func (c *Client) doRequest(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequestWithContext(ctx, method, "http://localhost"+path, body)
+	return c.doRequestWithHeaders(ctx, c.httpClient, method, path, body, nil)
+}
+
+func (c *Client) doRequestWithHeaders(ctx context.Context, httpClient *http.Client, method, path string, body io.Reader, headers http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+c.versionedPath(ctx, path), body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -112,8 +338,13 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body io.Rea
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	for key, values := range headers {
+		for _, value := range values {
+			req.Header.Set(key, value)
+		}
+	}
 
-	return c.httpClient.Do(req)
+	return httpClient.Do(req)
 }
 
 func (c *Client) Ping(ctx context.Context) error {
@@ -124,7 +355,7 @@ func (c *Client) Ping(ctx context.Context) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Docker ping failed with status: %d", resp.StatusCode)
+		return newAPIError("Docker ping", resp)
 	}
 
 	return nil
@@ -138,14 +369,14 @@ func (c *Client) GetInfo(ctx context.Context) (*Info, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Docker info failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError("Docker info", resp)
 	}
 
 	var info Info
 	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
 		return nil, fmt.Errorf("failed to decode Docker info: %w", err)
 	}
+	c.fillInfo(ctx, &info)
 
 	return &info, nil
 }
@@ -158,22 +389,34 @@ func (c *Client) GetVersion(ctx context.Context) (*Version, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Docker version failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError("Docker version", resp)
 	}
 
 	var version Version
 	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
 		return nil, fmt.Errorf("failed to decode Docker version: %w", err)
 	}
+	version.Engine = detectEngine(&version)
 
 	return &version, nil
 }
 
 func (c *Client) ListContainers(ctx context.Context, all bool) ([]Container, error) {
+	return c.ListContainersWithOptions(ctx, ContainerListOptions{All: all})
+}
+
+func (c *Client) ListContainersWithOptions(ctx context.Context, opts ContainerListOptions) ([]Container, error) {
+	query := url.Values{}
+	if opts.All {
+		query.Set("all", "true")
+	}
+	if err := opts.Filters.apply(query); err != nil {
+		return nil, err
+	}
+
 	path := "/containers/json"
-	if all {
-		path += "?all=true"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
 	}
 
 	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
@@ -183,8 +426,7 @@ func (c *Client) ListContainers(ctx context.Context, all bool) ([]Container, err
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("list containers failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError("list containers", resp)
 	}
 
 	var containers []Container
@@ -195,6 +437,75 @@ func (c *Client) ListContainers(ctx context.Context, all bool) ([]Container, err
 	return containers, nil
 }
 
+func (c *Client) InspectContainer(ctx context.Context, containerID string) (*ContainerJSON, error) {
+	path := fmt.Sprintf("/containers/%s/json", containerID)
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("inspect container", resp)
+	}
+
+	var container ContainerJSON
+	if err := json.NewDecoder(resp.Body).Decode(&container); err != nil {
+		return nil, fmt.Errorf("failed to decode container: %w", err)
+	}
+
+	return &container, nil
+}
+
+// InspectContainerRaw returns the unmodified inspect document, including
+// fields the ContainerJSON type does not model.
+func (c *Client) InspectContainerRaw(ctx context.Context, containerID string) (json.RawMessage, error) {
+	path := fmt.Sprintf("/containers/%s/json", containerID)
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("inspect container", resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read container: %w", err)
+	}
+
+	return json.RawMessage(data), nil
+}
+
+func (c *Client) UpdateContainer(ctx context.Context, containerID string, config UpdateConfig) ([]string, error) {
+	body, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal update config: %w", err)
+	}
+
+	path := fmt.Sprintf("/containers/%s/update", containerID)
+	resp, err := c.doRequest(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update container: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("update container", resp)
+	}
+
+	var updated struct {
+		Warnings []string `json:"Warnings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		return nil, fmt.Errorf("failed to decode update response: %w", err)
+	}
+
+	return updated.Warnings, nil
+}
+
 func (c *Client) StartContainer(ctx context.Context, containerID string) error {
 	path := fmt.Sprintf("/containers/%s/start", containerID)
 	resp, err := c.doRequest(ctx, http.MethodPost, path, nil)
@@ -204,8 +515,7 @@ func (c *Client) StartContainer(ctx context.Context, containerID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotModified {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("start container failed with status %d: %s", resp.StatusCode, string(body))
+		return newAPIError("start container", resp)
 	}
 
 	return nil
@@ -220,8 +530,7 @@ func (c *Client) StopContainer(ctx context.Context, containerID string, timeout
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotModified {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("stop container failed with status %d: %s", resp.StatusCode, string(body))
+		return newAPIError("stop container", resp)
 	}
 
 	return nil
@@ -236,8 +545,7 @@ func (c *Client) RestartContainer(ctx context.Context, containerID string, timeo
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("restart container failed with status %d: %s", resp.StatusCode, string(body))
+		return newAPIError("restart container", resp)
 	}
 
 	return nil
@@ -252,8 +560,7 @@ func (c *Client) PauseContainer(ctx context.Context, containerID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("pause container failed with status %d: %s", resp.StatusCode, string(body))
+		return newAPIError("pause container", resp)
 	}
 
 	return nil
@@ -268,8 +575,7 @@ func (c *Client) UnpauseContainer(ctx context.Context, containerID string) error
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unpause container failed with status %d: %s", resp.StatusCode, string(body))
+		return newAPIError("unpause container", resp)
 	}
 
 	return nil
@@ -284,23 +590,38 @@ func (c *Client) RemoveContainer(ctx context.Context, containerID string, force
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("remove container failed with status %d: %s", resp.StatusCode, string(body))
+		return newAPIError("remove container", resp)
 	}
 
 	return nil
 }
 
 func (c *Client) ListImages(ctx context.Context) ([]Image, error) {
-	resp, err := c.doRequest(ctx, http.MethodGet, "/images/json", nil)
+	return c.ListImagesWithOptions(ctx, ImageListOptions{})
+}
+
+func (c *Client) ListImagesWithOptions(ctx context.Context, opts ImageListOptions) ([]Image, error) {
+	query := url.Values{}
+	if opts.All {
+		query.Set("all", "true")
+	}
+	if err := opts.Filters.apply(query); err != nil {
+		return nil, err
+	}
+
+	path := "/images/json"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list images: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("list images failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError("list images", resp)
 	}
 
 	var images []Image
@@ -308,9 +629,91 @@ func (c *Client) ListImages(ctx context.Context) ([]Image, error) {
 		return nil, fmt.Errorf("failed to decode images: %w", err)
 	}
 
+	// Podman and newer API versions no longer report VirtualSize
+	for i := range images {
+		if images[i].VirtualSize == 0 {
+			images[i].VirtualSize = images[i].Size
+		}
+	}
+
 	return images, nil
 }
 
+func (c *Client) ImageExists(ctx context.Context, image string) (bool, error) {
+	path := fmt.Sprintf("/images/%s/json", image)
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, newAPIError("inspect image", resp)
+	}
+}
+
+func (c *Client) PullImage(ctx context.Context, image string, auth *AuthConfig) error {
+	repo, tag := SplitImageTag(image)
+	query := url.Values{}
+	query.Set("fromImage", repo)
+	query.Set("tag", tag)
+
+	headers, err := auth.headers()
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.doRequestWithHeaders(ctx, c.streamClient, http.MethodPost, "/images/create?"+query.Encode(), nil, headers)
+	if err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("pull image", resp)
+	}
+
+	// Errors during a pull are reported inside the progress stream.
+	return readJSONMessages(resp.Body, nil)
+}
+
+func (c *Client) CreateContainer(ctx context.Context, name string, config interface{}) (string, error) {
+	body, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal container config: %w", err)
+	}
+
+	path := "/containers/create"
+	if name != "" {
+		path += "?name=" + url.QueryEscape(name)
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", newAPIError("create container", resp)
+	}
+
+	var created struct {
+		ID       string   `json:"Id"`
+		Warnings []string `json:"Warnings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("failed to decode created container: %w", err)
+	}
+
+	return created.ID, nil
+}
+
 func (c *Client) RemoveImage(ctx context.Context, imageID string, force bool) error {
 	path := fmt.Sprintf("/images/%s?force=%t", imageID, force)
 	resp, err := c.doRequest(ctx, http.MethodDelete, path, nil)
@@ -320,8 +723,69 @@ func (c *Client) RemoveImage(ctx context.Context, imageID string, force bool) er
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("remove image failed with status %d: %s", resp.StatusCode, string(body))
+		return newAPIError("remove image", resp)
+	}
+
+	return nil
+}
+
+func (c *Client) InspectVolume(ctx context.Context, name string) (*Volume, error) {
+	path := fmt.Sprintf("/volumes/%s", name)
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect volume: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("inspect volume", resp)
+	}
+
+	var volume Volume
+	if err := json.NewDecoder(resp.Body).Decode(&volume); err != nil {
+		return nil, fmt.Errorf("failed to decode volume: %w", err)
+	}
+
+	return &volume, nil
+}
+
+func (c *Client) CreateVolume(ctx context.Context, name string, labels map[string]string) (*Volume, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"Name":   name,
+		"Labels": labels,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal volume config: %w", err)
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodPost, "/volumes/create", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create volume: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, newAPIError("create volume", resp)
+	}
+
+	var volume Volume
+	if err := json.NewDecoder(resp.Body).Decode(&volume); err != nil {
+		return nil, fmt.Errorf("failed to decode volume: %w", err)
+	}
+
+	return &volume, nil
+}
+
+func (c *Client) RemoveVolume(ctx context.Context, name string, force bool) error {
+	path := fmt.Sprintf("/volumes/%s?force=%t", name, force)
+	resp, err := c.doRequest(ctx, http.MethodDelete, path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to remove volume: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return newAPIError("remove volume", resp)
 	}
 
 	return nil
@@ -336,8 +800,7 @@ func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("get container logs failed with status %d: %s", resp.StatusCode, string(body))
+		return "", newAPIError("get container logs", resp)
 	}
 
 	logs, err := io.ReadAll(resp.Body)
@@ -0,0 +1,97 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Typed errors for Docker API responses
+
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrNotRunning   = errors.New("container is not running")
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// APIError is returned for any non-successful Docker API response. It
+// unwraps to one of the sentinel errors above when the status is known, so
+// callers can use errors.Is.
+type APIError struct {
+	Op         string
+	StatusCode int
+	Message    string
+	kind       error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s failed with status %d: %s", e.Op, e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// newAPIError reads the error body of resp and classifies it.
+func newAPIError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+
+	message := strings.TrimSpace(string(body))
+	var payload struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Message != "" {
+		message = payload.Message
+	}
+
+	return &APIError{
+		Op:         op,
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		kind:       classifyStatus(resp.StatusCode, message),
+	}
+}
+
+func classifyStatus(status int, message string) error {
+	switch status {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		if strings.Contains(message, "is not running") {
+			return ErrNotRunning
+		}
+		return ErrConflict
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusInternalServerError:
+		return classifyServerError(message)
+	}
+	return nil
+}
+
+// classifyServerError maps 500 responses that Podman's compat API returns
+// where Docker would use a 4xx status.
+func classifyServerError(message string) error {
+	lower := strings.ToLower(message)
+	switch {
+	case strings.Contains(lower, "no such container"),
+		strings.Contains(lower, "no such image"),
+		strings.Contains(lower, "no such volume"):
+		return ErrNotFound
+	case strings.Contains(lower, "is not running"):
+		return ErrNotRunning
+	case strings.Contains(lower, "container state improper"),
+		strings.Contains(lower, "is already"),
+		strings.Contains(lower, "in use"):
+		return ErrConflict
+	}
+	return nil
+}
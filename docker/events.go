@@ -0,0 +1,68 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Docker event stream
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type Event struct {
+	Type     string     `json:"Type"`
+	Action   string     `json:"Action"`
+	Actor    EventActor `json:"Actor"`
+	Time     int64      `json:"time"`
+	TimeNano int64      `json:"timeNano"`
+}
+
+type EventActor struct {
+	ID         string            `json:"ID"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+// Events follows /events and passes each event to fn until ctx is cancelled
+// or the daemon closes the stream. It returns nil only when ctx is done.
+func (c *Client) Events(ctx context.Context, filters Filters, fn func(Event)) error {
+	query := url.Values{}
+	if err := filters.apply(query); err != nil {
+		return err
+	}
+
+	path := "/events"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequestWithHeaders(ctx, c.streamClient, http.MethodGet, path, nil, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to follow events: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("follow events", resp)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event Event
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err == io.EOF {
+				return fmt.Errorf("event stream closed")
+			}
+			return fmt.Errorf("failed to decode event: %w", err)
+		}
+		fn(event)
+	}
+}
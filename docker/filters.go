@@ -0,0 +1,46 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: List filters passed to the Docker API
+
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Filters maps a Docker filter name (label, status, name, ancestor, network,
+// dangling, reference, ...) to the values to match.
+type Filters map[string][]string
+
+type ContainerListOptions struct {
+	All     bool
+	Filters Filters
+}
+
+type ImageListOptions struct {
+	All     bool
+	Filters Filters
+}
+
+func (f Filters) Add(name string, values ...string) {
+	for _, value := range values {
+		if value != "" {
+			f[name] = append(f[name], value)
+		}
+	}
+}
+
+func (f Filters) apply(query url.Values) error {
+	if len(f) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("failed to encode filters: %w", err)
+	}
+
+	query.Set("filters", string(data))
+	return nil
+}
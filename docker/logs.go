@@ -0,0 +1,168 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Reading and demultiplexing container log streams
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+
+	// maxLogLine caps how much of a line without a newline is buffered before
+	// it is passed on as a line of its own.
+	maxLogLine = 64 * 1024
+)
+
+// LogLine is one line of container output. Time is the daemon's timestamp
+// for the line.
+type LogLine struct {
+	Stream string
+	Time   time.Time
+	Text   string
+}
+
+// FollowLogs streams the stdout and stderr of a container from since onwards
+// and passes each line to fn. Containers created with a TTY have a single raw
+// stream, which is reported as stdout. It returns nil when the container
+// stops or ctx is cancelled.
+func (c *Client) FollowLogs(ctx context.Context, containerID string, since time.Time, tty bool, fn func(LogLine)) error {
+	query := url.Values{}
+	query.Set("follow", "true")
+	if !since.IsZero() {
+		query.Set("since", fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()))
+	}
+	return c.readLogs(ctx, c.streamClient, containerID, query, tty, fn)
+}
+
+// TailLogs passes the last tail lines of a container's output to fn.
+func (c *Client) TailLogs(ctx context.Context, containerID string, tail int, tty bool, fn func(LogLine)) error {
+	query := url.Values{}
+	query.Set("tail", strconv.Itoa(tail))
+	return c.readLogs(ctx, c.httpClient, containerID, query, tty, fn)
+}
+
+func (c *Client) readLogs(ctx context.Context, httpClient *http.Client, containerID string, query url.Values, tty bool, fn func(LogLine)) error {
+	query.Set("stdout", "true")
+	query.Set("stderr", "true")
+	query.Set("timestamps", "true")
+
+	path := fmt.Sprintf("/containers/%s/logs?%s", containerID, query.Encode())
+	resp, err := c.doRequestWithHeaders(ctx, httpClient, http.MethodGet, path, nil, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to read container logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("read container logs", resp)
+	}
+
+	stdout := &lineWriter{stream: StreamStdout, fn: fn}
+	stderr := &lineWriter{stream: StreamStderr, fn: fn}
+	defer stdout.flush()
+	defer stderr.flush()
+
+	if tty {
+		_, err = io.Copy(stdout, resp.Body)
+	} else {
+		err = demultiplex(resp.Body, stdout, stderr)
+	}
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("failed to read container logs: %w", err)
+	}
+	return nil
+}
+
+// demultiplex splits the framed log stream of a non-TTY container. Each frame
+// has an 8 byte header holding the stream type and the payload length.
+func demultiplex(r io.Reader, stdout, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var dst io.Writer
+		switch header[0] {
+		case 0, 1:
+			dst = stdout
+		case 2:
+			dst = stderr
+		default:
+			return fmt.Errorf("unexpected stream type %d", header[0])
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(dst, r, size); err != nil {
+			return err
+		}
+	}
+}
+
+// lineWriter collects a stream's output and passes it on line by line, since
+// frames do not necessarily end at line boundaries.
+type lineWriter struct {
+	stream string
+	fn     func(LogLine)
+	buf    []byte
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.buf = append(lw.buf, p...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		lw.emit(lw.buf[:i])
+		lw.buf = lw.buf[i+1:]
+	}
+	if len(lw.buf) >= maxLogLine {
+		lw.emit(lw.buf)
+		lw.buf = nil
+	}
+	return len(p), nil
+}
+
+func (lw *lineWriter) flush() {
+	if len(lw.buf) > 0 {
+		lw.emit(lw.buf)
+		lw.buf = nil
+	}
+}
+
+// emit splits off the RFC 3339 timestamp the daemon puts in front of each
+// line when timestamps are requested.
+func (lw *lineWriter) emit(raw []byte) {
+	line := strings.TrimSuffix(string(raw), "\r")
+	ts := time.Now()
+	if stamp, rest, ok := strings.Cut(line, " "); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+			ts = parsed
+			line = rest
+		}
+	} else if parsed, err := time.Parse(time.RFC3339Nano, line); err == nil {
+		ts = parsed
+		line = ""
+	}
+
+	lw.fn(LogLine{Stream: lw.stream, Time: ts, Text: line})
+}
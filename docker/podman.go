@@ -0,0 +1,120 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Container engine detection and Podman compatibility
+
+package docker
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	PodmanSocketPath = "/run/podman/podman.sock"
+
+	EngineDocker = "docker"
+	EnginePodman = "podman"
+
+	// detectTimeout bounds the ping used to pick a socket at startup.
+	detectTimeout = 2 * time.Second
+)
+
+type Component struct {
+	Name    string `json:"Name"`
+	Version string `json:"Version"`
+}
+
+// NewLocalClient picks the local container engine socket. Docker is used when
+// its socket answers a ping, Podman when only its socket answers. If neither
+// answers yet, as during boot, the Docker socket is kept when it exists so a
+// daemon that is still starting is picked up on later requests.
+func NewLocalClient() *Client {
+	ctx, cancel := context.WithTimeout(context.Background(), detectTimeout)
+	defer cancel()
+
+	client := NewClientWithSocket(DockerSocketPath)
+	if !socketExists(PodmanSocketPath) {
+		return client
+	}
+	if socketExists(DockerSocketPath) && client.Ping(ctx) == nil {
+		return client
+	}
+
+	podman := NewClientWithSocket(PodmanSocketPath)
+	if !socketExists(DockerSocketPath) || podman.Ping(ctx) == nil {
+		client.Close()
+		log.Printf("Using Podman socket at %s", PodmanSocketPath)
+		return podman
+	}
+
+	podman.Close()
+	return client
+}
+
+// Engine reports which engine answers on the client's connection. It is read
+// from /version during API version negotiation, which also catches Podman
+// behind a docker.sock symlink. Before a successful negotiation it returns an
+// empty string.
+func (c *Client) Engine(ctx context.Context) string {
+	if _, err := c.NegotiateAPIVersion(ctx); err != nil {
+		return ""
+	}
+
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	return c.engine
+}
+
+// IsPodman reports whether the client is talking to Podman.
+func (c *Client) IsPodman(ctx context.Context) bool {
+	return c.Engine(ctx) == EnginePodman
+}
+
+// detectEngine identifies Podman by the component list or platform name it
+// reports in /version.
+func detectEngine(version *Version) string {
+	for _, component := range version.Components {
+		if strings.Contains(strings.ToLower(component.Name), "podman") {
+			return EnginePodman
+		}
+	}
+	if strings.Contains(strings.ToLower(version.Platform.Name), "podman") {
+		return EnginePodman
+	}
+	return EngineDocker
+}
+
+// fillInfo completes fields that Podman's compat /info may leave out, so
+// callers see the same shape from either engine.
+func (c *Client) fillInfo(ctx context.Context, info *Info) {
+	info.Engine = c.Engine(ctx)
+
+	counted := info.ContainersRunning + info.ContainersPaused + info.ContainersStopped
+	if info.Containers > 0 && counted == 0 {
+		containers, err := c.ListContainers(ctx, true)
+		if err != nil {
+			return
+		}
+		for _, container := range containers {
+			switch container.State {
+			case "running":
+				info.ContainersRunning++
+			case "paused":
+				info.ContainersPaused++
+			default:
+				info.ContainersStopped++
+			}
+		}
+	}
+
+	if info.Name == "" && c.socketPath != "" {
+		info.Name, _ = os.Hostname()
+	}
+}
+
+func socketExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
@@ -0,0 +1,158 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Registry authentication and image reference helpers
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const DefaultRegistry = "docker.io"
+
+type AuthConfig struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	ServerAddress string `json:"serveraddress,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+type AuthResponse struct {
+	Status        string `json:"Status"`
+	IdentityToken string `json:"IdentityToken,omitempty"`
+}
+
+// JSONMessage is one line of the progress stream returned by pull, push,
+// build and load operations.
+type JSONMessage struct {
+	ID             string `json:"id,omitempty"`
+	Status         string `json:"status,omitempty"`
+	Progress       string `json:"progress,omitempty"`
+	ProgressDetail struct {
+		Current int64 `json:"current,omitempty"`
+		Total   int64 `json:"total,omitempty"`
+	} `json:"progressDetail,omitempty"`
+	Stream      string `json:"stream,omitempty"`
+	Error       string `json:"error,omitempty"`
+	ErrorDetail *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail,omitempty"`
+	Aux json.RawMessage `json:"aux,omitempty"`
+}
+
+func (a *AuthConfig) headers() (http.Header, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode registry auth: %w", err)
+	}
+
+	headers := http.Header{}
+	headers.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(data))
+	return headers, nil
+}
+
+func (c *Client) Auth(ctx context.Context, auth *AuthConfig) (*AuthResponse, error) {
+	body, err := json.Marshal(auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal auth config: %w", err)
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodPost, "/auth", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate with registry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return nil, newAPIError("registry auth", resp)
+	}
+
+	var authResp AuthResponse
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
+			return nil, fmt.Errorf("failed to decode auth response: %w", err)
+		}
+	}
+
+	return &authResp, nil
+}
+
+// RegistryHost returns the registry hostname an image reference is pulled
+// from, following Docker's rule that the first path component is a registry
+// only if it contains a dot or a port, or is "localhost".
+func RegistryHost(image string) string {
+	first, _, found := strings.Cut(image, "/")
+	if !found {
+		return DefaultRegistry
+	}
+	if strings.ContainsAny(first, ".:") || first == "localhost" {
+		return NormalizeRegistryHost(first)
+	}
+	return DefaultRegistry
+}
+
+// NormalizeRegistryHost strips schemes and paths from a registry address and
+// folds the Docker Hub aliases into DefaultRegistry.
+func NormalizeRegistryHost(address string) string {
+	host := strings.TrimSpace(strings.ToLower(address))
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	host, _, _ = strings.Cut(host, "/")
+
+	switch host {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return DefaultRegistry
+	}
+	return host
+}
+
+// SplitImageTag splits an image reference into repository and tag, defaulting
+// the tag to "latest". Digest references are returned unchanged with an empty
+// tag.
+func SplitImageTag(image string) (string, string) {
+	if strings.Contains(image, "@") {
+		return image, ""
+	}
+
+	lastSlash := strings.LastIndex(image, "/")
+	if i := strings.LastIndex(image, ":"); i > lastSlash {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+// readJSONMessages consumes a progress stream, passing each message to fn
+// when it is non-nil, and returns the first error reported by the daemon.
+func readJSONMessages(r io.Reader, fn func(JSONMessage)) error {
+	decoder := json.NewDecoder(r)
+	for {
+		var msg JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode progress stream: %w", err)
+		}
+
+		if fn != nil {
+			fn(msg)
+		}
+
+		if msg.ErrorDetail != nil && msg.ErrorDetail.Message != "" {
+			return fmt.Errorf("%s", msg.ErrorDetail.Message)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("%s", msg.Error)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: One-shot container resource usage statistics
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type Stats struct {
+	Read        string                  `json:"read"`
+	PreRead     string                  `json:"preread"`
+	CPUStats    CPUStats                `json:"cpu_stats"`
+	PreCPUStats CPUStats                `json:"precpu_stats"`
+	MemoryStats MemoryStats             `json:"memory_stats"`
+	Networks    map[string]NetworkStats `json:"networks"`
+	BlkioStats  BlkioStats              `json:"blkio_stats"`
+}
+
+type CPUStats struct {
+	CPUUsage struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  uint32 `json:"online_cpus"`
+}
+
+type MemoryStats struct {
+	Usage uint64            `json:"usage"`
+	Limit uint64            `json:"limit"`
+	Stats map[string]uint64 `json:"stats"`
+}
+
+type NetworkStats struct {
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+}
+
+type BlkioStats struct {
+	IoServiceBytesRecursive []BlkioEntry `json:"io_service_bytes_recursive"`
+}
+
+type BlkioEntry struct {
+	Op    string `json:"op"`
+	Value uint64 `json:"value"`
+}
+
+// ContainerStats returns a single stats sample. The daemon takes about a
+// second to answer because it measures CPU usage over an interval.
+func (c *Client) ContainerStats(ctx context.Context, containerID string) (*Stats, error) {
+	path := fmt.Sprintf("/containers/%s/stats?stream=false", containerID)
+	resp, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("get container stats", resp)
+	}
+
+	var stats Stats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to decode container stats: %w", err)
+	}
+
+	return &stats, nil
+}
+
+// CPUPercent computes usage the way `docker stats` does, where 100% is one
+// fully used CPU.
+func (s *Stats) CPUPercent() float64 {
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpus == 0 {
+		cpus = 1
+	}
+
+	return cpuDelta / systemDelta * cpus * 100
+}
+
+// MemoryUsage excludes inactive page cache, as `docker stats` does. It is
+// reported as inactive_file on cgroup v2 and as total_inactive_file on
+// cgroup v1.
+func (s *Stats) MemoryUsage() uint64 {
+	usage := s.MemoryStats.Usage
+	cache, ok := s.MemoryStats.Stats["inactive_file"]
+	if !ok {
+		cache = s.MemoryStats.Stats["total_inactive_file"]
+	}
+	if cache < usage {
+		return usage - cache
+	}
+	return usage
+}
+
+// NetworkBytes sums received and transmitted bytes over all interfaces.
+func (s *Stats) NetworkBytes() (rx, tx uint64) {
+	for _, n := range s.Networks {
+		rx += n.RxBytes
+		tx += n.TxBytes
+	}
+	return rx, tx
+}
+
+// BlockIO sums bytes read from and written to block devices.
+func (s *Stats) BlockIO() (read, write uint64) {
+	for _, entry := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			read += entry.Value
+		case "write":
+			write += entry.Value
+		}
+	}
+	return read, write
+}
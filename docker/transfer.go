@@ -0,0 +1,94 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Image export to and import from tarballs
+
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SaveImages streams a tarball of the given images from /images/get into w
+// and returns the number of bytes written.
+func (c *Client) SaveImages(ctx context.Context, images []string, w io.Writer) (int64, error) {
+	query := url.Values{}
+	for _, image := range images {
+		query.Add("names", image)
+	}
+
+	resp, err := c.doRequestWithHeaders(ctx, c.streamClient, http.MethodGet, "/images/get?"+query.Encode(), nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save images: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, newAPIError("save images", resp)
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to write image tarball: %w", err)
+	}
+
+	return n, nil
+}
+
+// LoadImages posts an image tarball to /images/load and returns the names
+// of the loaded images. Progress messages are passed to fn when non-nil.
+func (c *Client) LoadImages(ctx context.Context, tarball io.Reader, fn func(JSONMessage)) ([]string, error) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/x-tar")
+
+	resp, err := c.doRequestWithHeaders(ctx, c.streamClient, http.MethodPost, "/images/load", tarball, headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load images: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("load images", resp)
+	}
+
+	var loaded []string
+	err = readJSONMessages(resp.Body, func(msg JSONMessage) {
+		for _, prefix := range []string{"Loaded image: ", "Loaded image ID: "} {
+			if strings.HasPrefix(msg.Stream, prefix) {
+				loaded = append(loaded, strings.TrimSpace(strings.TrimPrefix(msg.Stream, prefix)))
+			}
+		}
+		if fn != nil {
+			fn(msg)
+		}
+	})
+	if err != nil {
+		return loaded, err
+	}
+
+	return loaded, nil
+}
+
+// TagImage adds the reference repo:tag to an existing image.
+func (c *Client) TagImage(ctx context.Context, image, reference string) error {
+	repo, tag := SplitImageTag(reference)
+	query := url.Values{}
+	query.Set("repo", repo)
+	query.Set("tag", tag)
+
+	path := fmt.Sprintf("/images/%s/tag?%s", image, query.Encode())
+	resp, err := c.doRequest(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to tag image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return newAPIError("tag image", resp)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Docker API version negotiation
+
+package docker
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// MaxAPIVersion is the newest Docker API version this client is written
+// against. Older daemons are spoken to at their own version.
+const MaxAPIVersion = "1.43"
+
+// NegotiateAPIVersion asks the daemon for its API version via /version and
+// pins the lower of it and MaxAPIVersion for all later requests.
+func (c *Client) NegotiateAPIVersion(ctx context.Context) (string, error) {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+
+	if c.apiVersion != "" {
+		return c.apiVersion, nil
+	}
+
+	version, err := c.GetVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	negotiated := MaxAPIVersion
+	if version.APIVersion != "" && compareAPIVersions(version.APIVersion, MaxAPIVersion) < 0 {
+		negotiated = version.APIVersion
+	}
+
+	c.apiVersion = negotiated
+	c.engine = version.Engine
+	return negotiated, nil
+}
+
+// APIVersion returns the pinned API version, or an empty string before
+// negotiation has succeeded.
+func (c *Client) APIVersion() string {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	return c.apiVersion
+}
+
+// versionedPath prefixes path with the negotiated API version. The ping and
+// version endpoints are left unversioned so they can be used to negotiate.
+// If negotiation fails the path is returned as is, letting the request itself
+// report the connection error.
+func (c *Client) versionedPath(ctx context.Context, path string) string {
+	if path == "/_ping" || path == "/version" {
+		return path
+	}
+
+	version, err := c.NegotiateAPIVersion(ctx)
+	if err != nil || version == "" {
+		return path
+	}
+
+	return "/v" + version + path
+}
+
+// compareAPIVersions compares two "major.minor" versions, returning -1, 0 or 1.
+func compareAPIVersions(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var av, bv int
+		if i < len(aParts) {
+			av, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bv, _ = strconv.Atoi(bParts[i])
+		}
+		if av < bv {
+			return -1
+		}
+		if av > bv {
+			return 1
+		}
+	}
+	return 0
+}
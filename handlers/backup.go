@@ -0,0 +1,257 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handlers for volume backup and restore endpoints
+
+package handlers
+
+import (
+	"bluenode-helper/backup"
+	"bluenode-helper/database"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+type BackupHandler struct {
+	manager *backup.Manager
+	store   *database.BackupStore
+}
+
+type CreateBackupRequest struct {
+	Container   string   `json:"container"`
+	Consistency string   `json:"consistency,omitempty"`
+	Mounts      []string `json:"mounts,omitempty"`
+}
+
+type RestoreBackupRequest struct {
+	BackupID    int    `json:"backup_id"`
+	Consistency string `json:"consistency,omitempty"`
+	Container   string `json:"container,omitempty"`
+}
+
+type ExportBundleRequest struct {
+	Container   string `json:"container"`
+	Path        string `json:"path"`
+	Consistency string `json:"consistency,omitempty"`
+	Overwrite   bool   `json:"overwrite,omitempty"`
+}
+
+type ImportBundleRequest struct {
+	Path    string            `json:"path"`
+	Name    string            `json:"name,omitempty"`
+	PortMap map[string]string `json:"port_map,omitempty"`
+	PathMap map[string]string `json:"path_map,omitempty"`
+	DataDir string            `json:"data_dir,omitempty"`
+	Start   bool              `json:"start,omitempty"`
+}
+
+func NewBackupHandler(manager *backup.Manager, store *database.BackupStore) *BackupHandler {
+	return &BackupHandler{
+		manager: manager,
+		store:   store,
+	}
+}
+
+func (h *BackupHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	backups, err := h.store.List(r.URL.Query().Get("container"), limit)
+	if err != nil {
+		log.Printf("Failed to list backups: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, backups)
+}
+
+func (h *BackupHandler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req CreateBackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Container == "" {
+		writeError(w, http.StatusBadRequest, "Container is required")
+		return
+	}
+
+	backups, err := h.manager.Backup(r.Context(), req.Container, backup.BackupOptions{
+		Consistency:  req.Consistency,
+		Destinations: req.Mounts,
+	})
+	if err != nil {
+		log.Printf("Failed to back up container %s: %v", req.Container, err)
+		writeDockerError(w, err)
+		return
+	}
+
+	writeSuccess(w, backups)
+}
+
+func (h *BackupHandler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req RestoreBackupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.BackupID <= 0 {
+		writeError(w, http.StatusBadRequest, "Backup ID is required")
+		return
+	}
+
+	restored, err := h.manager.Restore(r.Context(), req.BackupID, backup.RestoreOptions{
+		Consistency: req.Consistency,
+		Container:   req.Container,
+	})
+	if err != nil {
+		log.Printf("Failed to restore backup %d: %v", req.BackupID, err)
+		writeDockerError(w, err)
+		return
+	}
+
+	writeSuccess(w, map[string]interface{}{
+		"status": "restored",
+		"backup": restored,
+	})
+}
+
+func (h *BackupHandler) DeleteBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "Backup ID is required")
+		return
+	}
+
+	if err := h.manager.Delete(id); err != nil {
+		log.Printf("Failed to delete backup %d: %v", id, err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeSuccess(w, map[string]interface{}{
+		"status": "deleted",
+		"id":     id,
+	})
+}
+
+func (h *BackupHandler) ExportBundle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req ExportBundleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Container == "" {
+		writeError(w, http.StatusBadRequest, "Container is required")
+		return
+	}
+	if !filepath.IsAbs(req.Path) {
+		writeError(w, http.StatusBadRequest, "An absolute bundle path is required")
+		return
+	}
+	if _, err := os.Stat(req.Path); err == nil && !req.Overwrite {
+		writeError(w, http.StatusConflict, "Bundle file already exists")
+		return
+	}
+
+	manifest, err := h.manager.ExportBundle(r.Context(), req.Container, req.Path, backup.BundleExportOptions{
+		Consistency: req.Consistency,
+	})
+	if err != nil {
+		log.Printf("Failed to export bundle for %s: %v", req.Container, err)
+		writeDockerError(w, err)
+		return
+	}
+
+	writeSuccess(w, map[string]interface{}{
+		"path":     req.Path,
+		"manifest": manifest,
+	})
+}
+
+func (h *BackupHandler) ImportBundle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req ImportBundleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !filepath.IsAbs(req.Path) {
+		writeError(w, http.StatusBadRequest, "An absolute bundle path is required")
+		return
+	}
+	if req.DataDir != "" && !filepath.IsAbs(req.DataDir) {
+		writeError(w, http.StatusBadRequest, "Data directory must be absolute")
+		return
+	}
+	for from, to := range req.PathMap {
+		if !filepath.IsAbs(from) || !filepath.IsAbs(to) {
+			writeError(w, http.StatusBadRequest, "Path map entries must be absolute paths")
+			return
+		}
+	}
+
+	result, err := h.manager.ImportBundle(r.Context(), req.Path, backup.BundleImportOptions{
+		Name:    req.Name,
+		PortMap: req.PortMap,
+		PathMap: req.PathMap,
+		DataDir: req.DataDir,
+		Start:   req.Start,
+	})
+	if err != nil {
+		log.Printf("Failed to import bundle %s: %v", req.Path, err)
+		writeDockerError(w, err)
+		return
+	}
+
+	writeSuccess(w, result)
+}
+
+func (h *BackupHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/docker/backups", h.ListBackups)
+	mux.HandleFunc("/docker/backups/create", h.CreateBackup)
+	mux.HandleFunc("/docker/backups/restore", h.RestoreBackup)
+	mux.HandleFunc("/docker/backups/delete", h.DeleteBackup)
+	mux.HandleFunc("/docker/migrations/export", h.ExportBundle)
+	mux.HandleFunc("/docker/migrations/import", h.ImportBundle)
+}
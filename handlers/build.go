@@ -0,0 +1,180 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handler for building images from a Dockerfile context
+
+package handlers
+
+import (
+	"bluenode-helper/backup"
+	"bluenode-helper/docker"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxBuildOutput caps the build log kept for non-streamed responses.
+const maxBuildOutput = 256 * 1024
+
+type BuildImageResponse struct {
+	ImageID    string   `json:"image_id,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	FailedStep string   `json:"failed_step,omitempty"`
+	Output     string   `json:"output,omitempty"`
+}
+
+// BuildImage builds an image from either an uploaded tar context (the
+// request body) or a directory on the NAS given by the "path" parameter.
+func (h *DockerHandler) BuildImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	opts := docker.BuildOptions{
+		Tags:        query["tag"],
+		Dockerfile:  query.Get("dockerfile"),
+		Target:      query.Get("target"),
+		NoCache:     query.Get("nocache") == "true",
+		Pull:        query.Get("pull") == "true",
+		BuildArgs:   make(map[string]string),
+		AuthConfigs: h.allAuthConfigs(),
+	}
+	for _, arg := range query["buildarg"] {
+		key, value, found := strings.Cut(arg, "=")
+		if !found || key == "" {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid build arg: %s", arg))
+			return
+		}
+		opts.BuildArgs[key] = value
+	}
+
+	var buildContext io.Reader = r.Body
+	if dir := query.Get("path"); dir != "" {
+		if err := validateBuildDir(dir, opts.Dockerfile); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(backup.WriteArchive(dir, pw))
+		}()
+		defer pr.Close()
+		buildContext = pr
+	} else if r.ContentLength == 0 {
+		writeError(w, http.StatusBadRequest, "A tar build context or a path is required")
+		return
+	}
+
+	if wantsStream(r) {
+		stream := newStreamWriter(w)
+		result, err := client.BuildImage(r.Context(), buildContext, opts, func(msg docker.JSONMessage) {
+			stream.send(msg)
+		})
+		if err != nil {
+			log.Printf("Failed to build image: %v", err)
+			stream.fail(err.Error(), buildResponse(result, opts.Tags, ""))
+			return
+		}
+		stream.success(buildResponse(result, opts.Tags, ""))
+		return
+	}
+
+	var output strings.Builder
+	result, err := client.BuildImage(r.Context(), buildContext, opts, func(msg docker.JSONMessage) {
+		if output.Len() < maxBuildOutput {
+			output.WriteString(msg.Stream)
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to build image: %v", err)
+		if result == nil {
+			writeDockerError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, APIResponse{
+			Success: false,
+			Error:   err.Error(),
+			Data:    buildResponse(result, opts.Tags, output.String()),
+		})
+		return
+	}
+
+	writeSuccess(w, buildResponse(result, opts.Tags, output.String()))
+}
+
+func buildResponse(result *docker.BuildResult, tags []string, output string) BuildImageResponse {
+	resp := BuildImageResponse{Tags: tags, Output: output}
+	if result != nil {
+		resp.ImageID = result.ImageID
+		resp.FailedStep = result.FailedStep
+	}
+	return resp
+}
+
+// allAuthConfigs returns every stored registry credential for pulling base
+// images during a build.
+func (h *DockerHandler) allAuthConfigs() map[string]docker.AuthConfig {
+	if h.registries == nil {
+		return nil
+	}
+
+	creds, err := h.registries.List()
+	if err != nil {
+		log.Printf("Failed to list registry credentials: %v", err)
+		return nil
+	}
+
+	configs := make(map[string]docker.AuthConfig)
+	for _, c := range creds {
+		cred, err := h.registries.Get(c.Registry)
+		if err != nil {
+			log.Printf("Failed to get registry credentials for %s: %v", c.Registry, err)
+			continue
+		}
+
+		// The builder looks Docker Hub credentials up by the legacy index URL
+		key := cred.Registry
+		if key == docker.DefaultRegistry {
+			key = "https://index.docker.io/v1/"
+		}
+		configs[key] = docker.AuthConfig{
+			Username:      cred.Username,
+			Password:      cred.Password,
+			ServerAddress: key,
+		}
+	}
+	return configs
+}
+
+func validateBuildDir(dir, dockerfile string) error {
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("build path must be absolute: %s", dir)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("build path unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("build path is not a directory: %s", dir)
+	}
+
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	if _, err := os.Stat(filepath.Join(dir, dockerfile)); err != nil {
+		return fmt.Errorf("%s not found in %s", dockerfile, dir)
+	}
+
+	return nil
+}
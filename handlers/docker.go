@@ -4,15 +4,19 @@
 package handlers
 
 import (
+	"bluenode-helper/database"
 	"bluenode-helper/docker"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 )
 
 type DockerHandler struct {
-	client *docker.Client
+	client     *docker.Client
+	registries *database.RegistryStore
+	endpoints  *endpointClients
 }
 
 type APIResponse struct {
@@ -21,9 +25,15 @@ type APIResponse struct {
 	Error   string      `json:"error,omitempty"`
 }
 
-func NewDockerHandler() *DockerHandler {
+type CreateContainerRequest struct {
+	Image string `json:"Image"`
+}
+
+func NewDockerHandler(client *docker.Client, registries *database.RegistryStore, endpoints *database.EndpointStore) *DockerHandler {
 	return &DockerHandler{
-		client: docker.NewClient(),
+		client:     client,
+		registries: registries,
+		endpoints:  newEndpointClients(endpoints),
 	}
 }
 
@@ -40,6 +50,24 @@ func writeError(w http.ResponseWriter, status int, message string) {
 	})
 }
 
+// writeDockerError maps typed Docker client errors to HTTP status codes,
+// falling back to 500 for anything unclassified.
+func writeDockerError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, docker.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, docker.ErrNotRunning), errors.Is(err, docker.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, docker.ErrBadRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, docker.ErrUnauthorized):
+		status = http.StatusUnauthorized
+	}
+
+	writeError(w, status, err.Error())
+}
+
 func writeSuccess(w http.ResponseWriter, data interface{}) {
 	writeJSON(w, http.StatusOK, APIResponse{
 		Success: true,
@@ -53,7 +81,12 @@ func (h *DockerHandler) Ping(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.client.Ping(r.Context()); err != nil {
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	if err := client.Ping(r.Context()); err != nil {
 		log.Printf("Docker ping failed: %v", err)
 		writeError(w, http.StatusServiceUnavailable, "Docker daemon is not accessible")
 		return
@@ -68,10 +101,15 @@ func (h *DockerHandler) Info(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	info, err := h.client.GetInfo(r.Context())
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	info, err := client.GetInfo(r.Context())
 	if err != nil {
 		log.Printf("Failed to get Docker info: %v", err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
@@ -84,13 +122,22 @@ func (h *DockerHandler) Version(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	version, err := h.client.GetVersion(r.Context())
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := client.GetVersion(r.Context())
 	if err != nil {
 		log.Printf("Failed to get Docker version: %v", err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
+	if negotiated, err := client.NegotiateAPIVersion(r.Context()); err == nil {
+		version.NegotiatedAPIVersion = negotiated
+	}
+
 	writeSuccess(w, version)
 }
 
@@ -100,15 +147,100 @@ func (h *DockerHandler) ListContainers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	all := r.URL.Query().Get("all") == "true"
-	containers, err := h.client.ListContainers(r.Context(), all)
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	opts := docker.ContainerListOptions{
+		All:     query.Get("all") == "true",
+		Filters: docker.Filters{},
+	}
+	for _, name := range []string{"label", "status", "name", "ancestor", "network"} {
+		opts.Filters.Add(name, query[name]...)
+	}
+
+	// Filtering on status only makes sense across stopped containers too
+	if len(opts.Filters["status"]) > 0 {
+		opts.All = true
+	}
+
+	containers, err := client.ListContainersWithOptions(r.Context(), opts)
 	if err != nil {
 		log.Printf("Failed to list containers: %v", err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
-	writeSuccess(w, containers)
+	if err := sortContainers(containers, query.Get("sort"), query.Get("order")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := paginate(w, r, containers)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeSuccess(w, page)
+}
+
+func (h *DockerHandler) CreateContainer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	// The body is the Docker container create config and is passed through
+	var config json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var req CreateContainerRequest
+	if err := json.Unmarshal(config, &req); err != nil || req.Image == "" {
+		writeError(w, http.StatusBadRequest, "Image is required")
+		return
+	}
+
+	exists, err := client.ImageExists(r.Context(), req.Image)
+	if err != nil {
+		log.Printf("Failed to check image %s: %v", req.Image, err)
+		writeDockerError(w, err)
+		return
+	}
+
+	if !exists || r.URL.Query().Get("pull") == "true" {
+		auth, err := h.authFor(req.Image)
+		if err != nil {
+			log.Printf("Failed to get registry credentials for %s: %v", req.Image, err)
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := client.PullImage(r.Context(), req.Image, auth); err != nil {
+			log.Printf("Failed to pull image %s: %v", req.Image, err)
+			writeDockerError(w, err)
+			return
+		}
+	}
+
+	name := r.URL.Query().Get("name")
+	containerID, err := client.CreateContainer(r.Context(), name, config)
+	if err != nil {
+		log.Printf("Failed to create container %s: %v", name, err)
+		writeDockerError(w, err)
+		return
+	}
+
+	writeSuccess(w, map[string]string{"status": "created", "container": containerID})
 }
 
 func (h *DockerHandler) StartContainer(w http.ResponseWriter, r *http.Request) {
@@ -117,15 +249,20 @@ func (h *DockerHandler) StartContainer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
 	containerID := r.URL.Query().Get("id")
 	if containerID == "" {
 		writeError(w, http.StatusBadRequest, "Container ID is required")
 		return
 	}
 
-	if err := h.client.StartContainer(r.Context(), containerID); err != nil {
+	if err := client.StartContainer(r.Context(), containerID); err != nil {
 		log.Printf("Failed to start container %s: %v", containerID, err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
@@ -138,6 +275,11 @@ func (h *DockerHandler) StopContainer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
 	containerID := r.URL.Query().Get("id")
 	if containerID == "" {
 		writeError(w, http.StatusBadRequest, "Container ID is required")
@@ -151,9 +293,9 @@ func (h *DockerHandler) StopContainer(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := h.client.StopContainer(r.Context(), containerID, timeout); err != nil {
+	if err := client.StopContainer(r.Context(), containerID, timeout); err != nil {
 		log.Printf("Failed to stop container %s: %v", containerID, err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
@@ -166,6 +308,11 @@ func (h *DockerHandler) RestartContainer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
 	containerID := r.URL.Query().Get("id")
 	if containerID == "" {
 		writeError(w, http.StatusBadRequest, "Container ID is required")
@@ -179,9 +326,9 @@ func (h *DockerHandler) RestartContainer(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	if err := h.client.RestartContainer(r.Context(), containerID, timeout); err != nil {
+	if err := client.RestartContainer(r.Context(), containerID, timeout); err != nil {
 		log.Printf("Failed to restart container %s: %v", containerID, err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
@@ -194,15 +341,20 @@ func (h *DockerHandler) PauseContainer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
 	containerID := r.URL.Query().Get("id")
 	if containerID == "" {
 		writeError(w, http.StatusBadRequest, "Container ID is required")
 		return
 	}
 
-	if err := h.client.PauseContainer(r.Context(), containerID); err != nil {
+	if err := client.PauseContainer(r.Context(), containerID); err != nil {
 		log.Printf("Failed to pause container %s: %v", containerID, err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
@@ -215,15 +367,20 @@ func (h *DockerHandler) UnpauseContainer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
 	containerID := r.URL.Query().Get("id")
 	if containerID == "" {
 		writeError(w, http.StatusBadRequest, "Container ID is required")
 		return
 	}
 
-	if err := h.client.UnpauseContainer(r.Context(), containerID); err != nil {
+	if err := client.UnpauseContainer(r.Context(), containerID); err != nil {
 		log.Printf("Failed to unpause container %s: %v", containerID, err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
@@ -236,6 +393,11 @@ func (h *DockerHandler) RemoveContainer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
 	containerID := r.URL.Query().Get("id")
 	if containerID == "" {
 		writeError(w, http.StatusBadRequest, "Container ID is required")
@@ -245,9 +407,9 @@ func (h *DockerHandler) RemoveContainer(w http.ResponseWriter, r *http.Request)
 	force := r.URL.Query().Get("force") == "true"
 	removeVolumes := r.URL.Query().Get("v") == "true"
 
-	if err := h.client.RemoveContainer(r.Context(), containerID, force, removeVolumes); err != nil {
+	if err := client.RemoveContainer(r.Context(), containerID, force, removeVolumes); err != nil {
 		log.Printf("Failed to remove container %s: %v", containerID, err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
@@ -260,6 +422,11 @@ func (h *DockerHandler) ContainerLogs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
 	containerID := r.URL.Query().Get("id")
 	if containerID == "" {
 		writeError(w, http.StatusBadRequest, "Container ID is required")
@@ -272,10 +439,10 @@ func (h *DockerHandler) ContainerLogs(w http.ResponseWriter, r *http.Request) {
 	}
 	timestamps := r.URL.Query().Get("timestamps") == "true"
 
-	logs, err := h.client.GetContainerLogs(r.Context(), containerID, tail, timestamps)
+	logs, err := client.GetContainerLogs(r.Context(), containerID, tail, timestamps)
 	if err != nil {
 		log.Printf("Failed to get logs for container %s: %v", containerID, err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
@@ -288,14 +455,84 @@ func (h *DockerHandler) ListImages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	images, err := h.client.ListImages(r.Context())
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	opts := docker.ImageListOptions{
+		All:     query.Get("all") == "true",
+		Filters: docker.Filters{},
+	}
+	opts.Filters.Add("label", query["label"]...)
+	opts.Filters.Add("reference", query["name"]...)
+	opts.Filters.Add("dangling", query.Get("dangling"))
+
+	images, err := client.ListImagesWithOptions(r.Context(), opts)
 	if err != nil {
 		log.Printf("Failed to list images: %v", err)
+		writeDockerError(w, err)
+		return
+	}
+
+	containers, err := client.ListContainers(r.Context(), true)
+	if err != nil {
+		log.Printf("Failed to list containers: %v", err)
+		writeDockerError(w, err)
+		return
+	}
+
+	items := buildImageList(images, containers)
+	if used := query.Get("used"); used != "" {
+		items = filterImagesByUse(items, used == "true")
+	}
+
+	if err := sortImages(items, query.Get("sort"), query.Get("order")); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := paginate(w, r, items)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeSuccess(w, page)
+}
+
+func (h *DockerHandler) PullImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	image := r.URL.Query().Get("image")
+	if image == "" {
+		writeError(w, http.StatusBadRequest, "Image is required")
+		return
+	}
+
+	auth, err := h.authFor(image)
+	if err != nil {
+		log.Printf("Failed to get registry credentials for %s: %v", image, err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	writeSuccess(w, images)
+	if err := client.PullImage(r.Context(), image, auth); err != nil {
+		log.Printf("Failed to pull image %s: %v", image, err)
+		writeDockerError(w, err)
+		return
+	}
+
+	writeSuccess(w, map[string]string{"status": "pulled", "image": image})
 }
 
 func (h *DockerHandler) RemoveImage(w http.ResponseWriter, r *http.Request) {
@@ -304,6 +541,11 @@ func (h *DockerHandler) RemoveImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
 	imageID := r.URL.Query().Get("id")
 	if imageID == "" {
 		writeError(w, http.StatusBadRequest, "Image ID is required")
@@ -312,9 +554,9 @@ func (h *DockerHandler) RemoveImage(w http.ResponseWriter, r *http.Request) {
 
 	force := r.URL.Query().Get("force") == "true"
 
-	if err := h.client.RemoveImage(r.Context(), imageID, force); err != nil {
+	if err := client.RemoveImage(r.Context(), imageID, force); err != nil {
 		log.Printf("Failed to remove image %s: %v", imageID, err)
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDockerError(w, err)
 		return
 	}
 
@@ -327,6 +569,7 @@ func (h *DockerHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/docker/version", h.Version)
 
 	mux.HandleFunc("/docker/containers", h.ListContainers)
+	mux.HandleFunc("/docker/containers/create", h.CreateContainer)
 	mux.HandleFunc("/docker/containers/start", h.StartContainer)
 	mux.HandleFunc("/docker/containers/stop", h.StopContainer)
 	mux.HandleFunc("/docker/containers/restart", h.RestartContainer)
@@ -334,7 +577,20 @@ func (h *DockerHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/docker/containers/unpause", h.UnpauseContainer)
 	mux.HandleFunc("/docker/containers/remove", h.RemoveContainer)
 	mux.HandleFunc("/docker/containers/logs", h.ContainerLogs)
+	mux.HandleFunc("/docker/containers/resources", h.ContainerResources)
 
 	mux.HandleFunc("/docker/images", h.ListImages)
+	mux.HandleFunc("/docker/images/pull", h.PullImage)
+	mux.HandleFunc("/docker/images/build", h.BuildImage)
+	mux.HandleFunc("/docker/images/export", h.ExportImages)
+	mux.HandleFunc("/docker/images/import", h.ImportImages)
 	mux.HandleFunc("/docker/images/remove", h.RemoveImage)
+
+	mux.HandleFunc("/docker/registries", h.ListRegistries)
+	mux.HandleFunc("/docker/registries/set", h.SetRegistry)
+	mux.HandleFunc("/docker/registries/delete", h.DeleteRegistry)
+
+	mux.HandleFunc("/docker/endpoints", h.ListEndpoints)
+	mux.HandleFunc("/docker/endpoints/set", h.SetEndpoint)
+	mux.HandleFunc("/docker/endpoints/delete", h.DeleteEndpoint)
 }
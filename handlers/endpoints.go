@@ -0,0 +1,220 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handlers for named Docker endpoint management
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/docker"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocalEndpoint is the reserved name of the Docker daemon on this machine.
+const LocalEndpoint = "local"
+
+type SetEndpointRequest struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	TLSVerify  *bool  `json:"tls_verify,omitempty"`
+	CACert     string `json:"ca_cert,omitempty"`
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+}
+
+// endpointClients caches one Docker client per named endpoint.
+type endpointClients struct {
+	store *database.EndpointStore
+
+	mu      sync.Mutex
+	clients map[string]*docker.Client
+}
+
+func newEndpointClients(store *database.EndpointStore) *endpointClients {
+	return &endpointClients{
+		store:   store,
+		clients: make(map[string]*docker.Client),
+	}
+}
+
+func (ec *endpointClients) get(name string) (*docker.Client, error) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	if client, ok := ec.clients[name]; ok {
+		return client, nil
+	}
+
+	endpoint, err := ec.store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newEndpointClient(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	ec.clients[name] = client
+	return client, nil
+}
+
+func (ec *endpointClients) invalidate(name string) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	if client, ok := ec.clients[name]; ok {
+		client.Close()
+		delete(ec.clients, name)
+	}
+}
+
+// newEndpointClient connects to an endpoint over TLS, verified against the
+// system roots unless a CA is stored. Plain TCP is only used when
+// tls_verify is turned off and no certificates are stored.
+func newEndpointClient(endpoint *database.DockerEndpoint) (*docker.Client, error) {
+	noCerts := endpoint.CACert == "" && endpoint.ClientCert == "" && endpoint.ClientKey == ""
+	if noCerts && !endpoint.TLSVerify && !strings.HasPrefix(endpoint.URL, "https://") {
+		return docker.NewClientWithEndpoint(endpoint.URL, nil)
+	}
+
+	tlsConfig, err := docker.NewTLSConfig(
+		[]byte(endpoint.CACert),
+		[]byte(endpoint.ClientCert),
+		[]byte(endpoint.ClientKey),
+		endpoint.TLSVerify,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return docker.NewClientWithEndpoint(endpoint.URL, tlsConfig)
+}
+
+// clientFor returns the Docker client selected by the "endpoint" query
+// parameter, defaulting to the local daemon. It writes an error response and
+// returns false when the endpoint is unknown.
+func (h *DockerHandler) clientFor(w http.ResponseWriter, r *http.Request) (*docker.Client, bool) {
+	name := r.URL.Query().Get("endpoint")
+	if name == "" || name == LocalEndpoint || h.endpoints == nil {
+		return h.client, true
+	}
+
+	client, err := h.endpoints.get(name)
+	if err != nil {
+		log.Printf("Failed to get Docker endpoint %s: %v", name, err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+
+	return client, true
+}
+
+func (h *DockerHandler) ListEndpoints(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	endpoints, err := h.endpoints.store.List()
+	if err != nil {
+		log.Printf("Failed to list Docker endpoints: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, endpoints)
+}
+
+func (h *DockerHandler) SetEndpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req SetEndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Name == "" || req.URL == "" {
+		writeError(w, http.StatusBadRequest, "Endpoint name and URL are required")
+		return
+	}
+
+	if req.Name == LocalEndpoint {
+		writeError(w, http.StatusBadRequest, "Endpoint name 'local' is reserved")
+		return
+	}
+
+	endpoint := &database.DockerEndpoint{
+		Name:       req.Name,
+		URL:        req.URL,
+		TLSVerify:  true,
+		CACert:     req.CACert,
+		ClientCert: req.ClientCert,
+		ClientKey:  req.ClientKey,
+	}
+	if req.TLSVerify != nil {
+		endpoint.TLSVerify = *req.TLSVerify
+	}
+
+	client, err := newEndpointClient(endpoint)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer client.Close()
+
+	// Make sure the endpoint is reachable before saving it
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		log.Printf("Docker endpoint %s is not reachable: %v", req.Name, err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.endpoints.store.Set(endpoint); err != nil {
+		log.Printf("Failed to save Docker endpoint %s: %v", req.Name, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.endpoints.invalidate(req.Name)
+
+	writeSuccess(w, map[string]string{
+		"status":   "saved",
+		"endpoint": req.Name,
+	})
+}
+
+func (h *DockerHandler) DeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Endpoint name is required")
+		return
+	}
+
+	if err := h.endpoints.store.Delete(name); err != nil {
+		log.Printf("Failed to delete Docker endpoint %s: %v", name, err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.endpoints.invalidate(name)
+
+	writeSuccess(w, map[string]string{
+		"status":   "deleted",
+		"endpoint": name,
+	})
+}
@@ -0,0 +1,170 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handlers for container health policy endpoints
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+type HealthHandler struct {
+	store *database.HealthStore
+}
+
+type SetHealthPolicyRequest struct {
+	ContainerName      string `json:"container_name"`
+	Enabled            *bool  `json:"enabled,omitempty"`
+	UnhealthyThreshold int    `json:"unhealthy_threshold,omitempty"`
+	MaxRestartsPerHour *int   `json:"max_restarts_per_hour,omitempty"`
+	BackoffSeconds     *int   `json:"backoff_seconds,omitempty"`
+	MaxBackoffSeconds  *int   `json:"max_backoff_seconds,omitempty"`
+	RestartOnExit      bool   `json:"restart_on_exit,omitempty"`
+}
+
+func NewHealthHandler(store *database.HealthStore) *HealthHandler {
+	return &HealthHandler{
+		store: store,
+	}
+}
+
+func (h *HealthHandler) ListPolicies(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	policies, err := h.store.ListPolicies()
+	if err != nil {
+		log.Printf("Failed to list health policies: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, policies)
+}
+
+func (h *HealthHandler) SetPolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req SetHealthPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.ContainerName == "" {
+		writeError(w, http.StatusBadRequest, "Container name is required")
+		return
+	}
+
+	if req.UnhealthyThreshold < 0 || negative(req.MaxRestartsPerHour) || negative(req.BackoffSeconds) || negative(req.MaxBackoffSeconds) {
+		writeError(w, http.StatusBadRequest, "Policy values must not be negative")
+		return
+	}
+
+	policy := &database.HealthPolicy{
+		ContainerName:      req.ContainerName,
+		Enabled:            true,
+		UnhealthyThreshold: 3,
+		MaxRestartsPerHour: 5,
+		BackoffSeconds:     30,
+		MaxBackoffSeconds:  600,
+		RestartOnExit:      req.RestartOnExit,
+	}
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+	if req.UnhealthyThreshold > 0 {
+		policy.UnhealthyThreshold = req.UnhealthyThreshold
+	}
+	// 0 turns these off, so only a missing value means the default
+	if req.MaxRestartsPerHour != nil {
+		policy.MaxRestartsPerHour = *req.MaxRestartsPerHour
+	}
+	if req.BackoffSeconds != nil {
+		policy.BackoffSeconds = *req.BackoffSeconds
+	}
+	if req.MaxBackoffSeconds != nil {
+		policy.MaxBackoffSeconds = *req.MaxBackoffSeconds
+	}
+
+	if err := h.store.SetPolicy(policy); err != nil {
+		log.Printf("Failed to set health policy for %s: %v", req.ContainerName, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	saved, err := h.store.GetPolicy(req.ContainerName)
+	if err != nil {
+		log.Printf("Failed to get health policy for %s: %v", req.ContainerName, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, saved)
+}
+
+func negative(value *int) bool {
+	return value != nil && *value < 0
+}
+
+func (h *HealthHandler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	name := r.URL.Query().Get("container")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Container name is required")
+		return
+	}
+
+	if err := h.store.DeletePolicy(name); err != nil {
+		log.Printf("Failed to delete health policy for %s: %v", name, err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeSuccess(w, map[string]string{
+		"status":    "deleted",
+		"container": name,
+	})
+}
+
+func (h *HealthHandler) ListActions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := 100
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+
+	actions, err := h.store.ListActions(r.URL.Query().Get("container"), limit)
+	if err != nil {
+		log.Printf("Failed to list health actions: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, actions)
+}
+
+func (h *HealthHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/docker/health/policies", h.ListPolicies)
+	mux.HandleFunc("/docker/health/policies/set", h.SetPolicy)
+	mux.HandleFunc("/docker/health/policies/delete", h.DeletePolicy)
+	mux.HandleFunc("/docker/health/actions", h.ListActions)
+}
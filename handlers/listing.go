@@ -0,0 +1,181 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Sorting, paging and enrichment of container and image lists
+
+package handlers
+
+import (
+	"bluenode-helper/docker"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImageListItem is an image together with the containers that use it.
+type ImageListItem struct {
+	docker.Image
+	UsedBy   []string `json:"UsedBy"`
+	Dangling bool     `json:"Dangling"`
+}
+
+// paginate applies the "limit" and "offset" query parameters and reports the
+// unpaged total in the X-Total-Count header, so the response body stays a
+// plain array.
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T) ([]T, error) {
+	total := len(items)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	offset, limit := 0, total
+	if o := r.URL.Query().Get("offset"); o != "" {
+		parsed, err := strconv.Atoi(o)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid offset: %s", o)
+		}
+		offset = parsed
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid limit: %s", l)
+		}
+		limit = parsed
+	}
+
+	if offset >= total {
+		return []T{}, nil
+	}
+	// Clamped before adding so a huge limit cannot overflow
+	if limit > total-offset {
+		limit = total - offset
+	}
+	return items[offset : offset+limit], nil
+}
+
+func sortContainers(containers []docker.Container, field, order string) error {
+	var less func(a, b docker.Container) bool
+	switch field {
+	case "":
+		return nil
+	case "name":
+		less = func(a, b docker.Container) bool { return containerName(a) < containerName(b) }
+	case "created":
+		less = func(a, b docker.Container) bool { return a.Created < b.Created }
+	case "status", "state":
+		less = func(a, b docker.Container) bool { return a.State < b.State }
+	case "image":
+		less = func(a, b docker.Container) bool { return a.Image < b.Image }
+	default:
+		return fmt.Errorf("invalid sort field: %s", field)
+	}
+
+	desc, err := descending(order)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(containers, func(i, j int) bool {
+		if desc {
+			return less(containers[j], containers[i])
+		}
+		return less(containers[i], containers[j])
+	})
+	return nil
+}
+
+func sortImages(images []ImageListItem, field, order string) error {
+	var less func(a, b ImageListItem) bool
+	switch field {
+	case "":
+		return nil
+	case "created":
+		less = func(a, b ImageListItem) bool { return a.Created < b.Created }
+	case "size":
+		less = func(a, b ImageListItem) bool { return a.Size < b.Size }
+	case "name", "tag":
+		less = func(a, b ImageListItem) bool { return imageName(a.Image) < imageName(b.Image) }
+	case "containers":
+		less = func(a, b ImageListItem) bool { return len(a.UsedBy) < len(b.UsedBy) }
+	default:
+		return fmt.Errorf("invalid sort field: %s", field)
+	}
+
+	desc, err := descending(order)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(images, func(i, j int) bool {
+		if desc {
+			return less(images[j], images[i])
+		}
+		return less(images[i], images[j])
+	})
+	return nil
+}
+
+func descending(order string) (bool, error) {
+	switch order {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid sort order: %s", order)
+}
+
+// buildImageList attaches the names of the containers using each image and
+// flags images that have no tag.
+func buildImageList(images []docker.Image, containers []docker.Container) []ImageListItem {
+	usedBy := make(map[string][]string)
+	for _, c := range containers {
+		usedBy[c.ImageID] = append(usedBy[c.ImageID], containerName(c))
+	}
+
+	items := make([]ImageListItem, 0, len(images))
+	for _, image := range images {
+		names := usedBy[image.ID]
+		if names == nil {
+			names = []string{}
+		}
+		items = append(items, ImageListItem{
+			Image:    image,
+			UsedBy:   names,
+			Dangling: isDangling(image),
+		})
+	}
+	return items
+}
+
+func filterImagesByUse(items []ImageListItem, used bool) []ImageListItem {
+	filtered := items[:0]
+	for _, item := range items {
+		if (len(item.UsedBy) > 0) == used {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+func isDangling(image docker.Image) bool {
+	for _, tag := range image.RepoTags {
+		if tag != "<none>:<none>" {
+			return false
+		}
+	}
+	return true
+}
+
+func containerName(c docker.Container) string {
+	if len(c.Names) == 0 {
+		return c.ID
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+func imageName(image docker.Image) string {
+	if len(image.RepoTags) == 0 {
+		return ""
+	}
+	return image.RepoTags[0]
+}
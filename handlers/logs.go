@@ -0,0 +1,251 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handlers for container log archival and search
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/docker"
+	"bluenode-helper/logs"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type LogsHandler struct {
+	store *database.LogStore
+	// archiver is nil when archival is disabled; archived lines can still
+	// be searched.
+	archiver *logs.Archiver
+}
+
+type LogTargetStatus struct {
+	database.LogTarget
+	Following bool `json:"following"`
+}
+
+type SetLogTargetRequest struct {
+	ContainerName string `json:"container_name"`
+	Enabled       *bool  `json:"enabled,omitempty"`
+}
+
+type LogUsageResponse struct {
+	database.LogUsage
+	MaxBytes  int64 `json:"max_bytes"`
+	Archiving bool  `json:"archiving"`
+}
+
+func NewLogsHandler(store *database.LogStore, archiver *logs.Archiver) *LogsHandler {
+	return &LogsHandler{
+		store:    store,
+		archiver: archiver,
+	}
+}
+
+func (h *LogsHandler) ListTargets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	targets, err := h.store.ListTargets()
+	if err != nil {
+		log.Printf("Failed to list log targets: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	following := map[string]bool{}
+	if h.archiver != nil {
+		following = h.archiver.Following()
+	}
+
+	statuses := make([]LogTargetStatus, len(targets))
+	for i, target := range targets {
+		statuses[i] = LogTargetStatus{
+			LogTarget: target,
+			Following: following[target.ContainerName],
+		}
+	}
+
+	writeSuccess(w, statuses)
+}
+
+func (h *LogsHandler) SetTarget(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req SetLogTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	name := strings.TrimPrefix(req.ContainerName, "/")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Container name is required")
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	target, err := h.store.SetTarget(name, enabled)
+	if err != nil {
+		log.Printf("Failed to set log target for %s: %v", name, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if h.archiver != nil {
+		h.archiver.Wake()
+	}
+
+	writeSuccess(w, target)
+}
+
+func (h *LogsHandler) DeleteTarget(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	name := strings.TrimPrefix(query.Get("container"), "/")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Container name is required")
+		return
+	}
+	purge := query.Get("purge") == "true"
+
+	if err := h.store.DeleteTarget(name, purge); err != nil {
+		log.Printf("Failed to delete log target for %s: %v", name, err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if h.archiver != nil {
+		h.archiver.Wake()
+	}
+
+	writeSuccess(w, map[string]interface{}{
+		"status":    "deleted",
+		"container": name,
+		"purged":    purge,
+	})
+}
+
+// Search queries archived lines across containers, newest first.
+func (h *LogsHandler) Search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	q := database.LogQuery{
+		Text:   query.Get("q"),
+		Stream: query.Get("stream"),
+	}
+
+	for _, value := range query["container"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimPrefix(strings.TrimSpace(name), "/"); name != "" {
+				q.Containers = append(q.Containers, name)
+			}
+		}
+	}
+
+	if q.Stream != "" && q.Stream != docker.StreamStdout && q.Stream != docker.StreamStderr {
+		writeError(w, http.StatusBadRequest, "Stream must be stdout or stderr")
+		return
+	}
+
+	if v := query.Get("to"); v != "" {
+		parsed, err := parseMetricsTime(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		q.To = parsed
+	}
+	if v := query.Get("range"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "Invalid range, use a duration such as 6h")
+			return
+		}
+		to := q.To
+		if to.IsZero() {
+			to = time.Now()
+		}
+		q.From = to.Add(-d)
+	}
+	if v := query.Get("from"); v != "" {
+		parsed, err := parseMetricsTime(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		q.From = parsed
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		writeError(w, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > database.MaxLogSearchLimit {
+			writeError(w, http.StatusBadRequest, "Limit must be between 1 and "+strconv.Itoa(database.MaxLogSearchLimit))
+			return
+		}
+		q.Limit = limit
+	}
+
+	entries, err := h.store.Search(q)
+	if err != nil {
+		log.Printf("Failed to search logs: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, entries)
+}
+
+func (h *LogsHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	usage, err := h.store.Usage()
+	if err != nil {
+		log.Printf("Failed to get log archive usage: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := LogUsageResponse{LogUsage: *usage}
+	if h.archiver != nil {
+		response.MaxBytes = h.archiver.MaxBytes()
+		response.Archiving = true
+	}
+
+	writeSuccess(w, response)
+}
+
+func (h *LogsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/docker/logs/targets", h.ListTargets)
+	mux.HandleFunc("/docker/logs/targets/set", h.SetTarget)
+	mux.HandleFunc("/docker/logs/targets/delete", h.DeleteTarget)
+	mux.HandleFunc("/docker/logs/search", h.Search)
+	mux.HandleFunc("/docker/logs/usage", h.Usage)
+}
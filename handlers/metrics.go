@@ -0,0 +1,138 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handler for container metrics history
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMetricsRange is the window returned when no range is given.
+const defaultMetricsRange = time.Hour
+
+type MetricsHandler struct {
+	store *database.MetricsStore
+}
+
+type MetricsResponse struct {
+	Container  string                  `json:"container"`
+	Resolution int64                   `json:"resolution"`
+	From       time.Time               `json:"from"`
+	To         time.Time               `json:"to"`
+	Points     []database.MetricsPoint `json:"points"`
+}
+
+func NewMetricsHandler(store *database.MetricsStore) *MetricsHandler {
+	return &MetricsHandler{
+		store: store,
+	}
+}
+
+// ContainerMetrics returns the stored history of one container, or the names
+// of all containers with history when no container is given.
+func (h *MetricsHandler) ContainerMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	name := strings.TrimPrefix(query.Get("container"), "/")
+	if name == "" {
+		names, err := h.store.ListContainers()
+		if err != nil {
+			log.Printf("Failed to list metrics containers: %v", err)
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeSuccess(w, names)
+		return
+	}
+
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		parsed, err := parseMetricsTime(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		to = parsed
+	}
+
+	from := to.Add(-defaultMetricsRange)
+	if v := query.Get("range"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "Invalid range, use a duration such as 6h")
+			return
+		}
+		from = to.Add(-d)
+	}
+	if v := query.Get("from"); v != "" {
+		parsed, err := parseMetricsTime(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		from = parsed
+	}
+	if !from.Before(to) {
+		writeError(w, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	resolution := h.store.ResolutionFor(from)
+	if v := query.Get("resolution"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || !h.store.HasResolution(time.Duration(seconds)*time.Second) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported resolution, available: %s", tierList(h.store.Tiers())))
+			return
+		}
+		resolution = time.Duration(seconds) * time.Second
+	}
+
+	points, err := h.store.Query(name, resolution, from, to)
+	if err != nil {
+		log.Printf("Failed to query metrics for %s: %v", name, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, MetricsResponse{
+		Container:  name,
+		Resolution: int64(resolution / time.Second),
+		From:       from.UTC(),
+		To:         to.UTC(),
+		Points:     points,
+	})
+}
+
+// parseMetricsTime accepts RFC 3339 timestamps and Unix seconds.
+func parseMetricsTime(v string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use RFC 3339 or Unix seconds", v)
+	}
+	return t, nil
+}
+
+func tierList(tiers []database.MetricsTier) string {
+	parts := make([]string, len(tiers))
+	for i, tier := range tiers {
+		parts[i] = strconv.Itoa(int(tier.Resolution / time.Second))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (h *MetricsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/docker/containers/metrics", h.ContainerMetrics)
+}
@@ -4,8 +4,10 @@
 package handlers
 
 import (
+	"bluenode-helper/assistant"
 	"bluenode-helper/database"
 	"bluenode-helper/ollama"
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -18,17 +20,26 @@ type OllamaHandler struct {
 	chatStore      *database.ChatStore
 	fileIndexStore *database.FileIndexStore
 	configStore    *database.ConfigStore
+	actionStore    *database.ActionStore
+	toolbox        *assistant.Toolbox
+	// ctx bounds work that outlives a request, such as title generation,
+	// so that it stops on shutdown
+	ctx context.Context
 }
 
 type ChatRequest struct {
-	Model     string `json:"model"`
-	Message   string `json:"message"`
-	SessionID string `json:"session_id,omitempty"`
+	Model     string            `json:"model"`
+	Message   string            `json:"message"`
+	SessionID string            `json:"session_id,omitempty"`
+	Images    []ImageAttachment `json:"images,omitempty"`
+	// Options override the session's generation options for this message.
+	Options database.GenerationOptions `json:"options,omitempty"`
 }
 
 type ChatSessionRequest struct {
-	Model string `json:"model"`
-	Title string `json:"title,omitempty"`
+	Model   string                     `json:"model"`
+	Title   string                     `json:"title,omitempty"`
+	Options database.GenerationOptions `json:"options,omitempty"`
 }
 
 type IndexFileRequest struct {
@@ -36,15 +47,36 @@ type IndexFileRequest struct {
 	Model    string `json:"model"`
 }
 
-func NewOllamaHandler(client *ollama.Client, chatStore *database.ChatStore, fileIndexStore *database.FileIndexStore, configStore *database.ConfigStore) *OllamaHandler {
+func NewOllamaHandler(ctx context.Context, client *ollama.Client, chatStore *database.ChatStore, fileIndexStore *database.FileIndexStore, configStore *database.ConfigStore, actionStore *database.ActionStore, toolbox *assistant.Toolbox) *OllamaHandler {
 	return &OllamaHandler{
 		client:         client,
 		chatStore:      chatStore,
 		fileIndexStore: fileIndexStore,
 		configStore:    configStore,
+		actionStore:    actionStore,
+		toolbox:        toolbox,
+		ctx:            ctx,
 	}
 }
 
+// defaultModel returns the configured default model for requests that do not
+// name one.
+func (h *OllamaHandler) defaultModel() string {
+	config, err := h.configStore.Get("ollama.default_model")
+	if err != nil {
+		return "qwen2.5:0.5b"
+	}
+	return config.Value
+}
+
+func (h *OllamaHandler) systemPrompt() string {
+	config, err := h.configStore.Get("ollama.system_prompt")
+	if err != nil || config.Value == "" {
+		return "You are BlueNode Helper, an AI assistant for the BlueNode Server OS."
+	}
+	return config.Value
+}
+
 func (h *OllamaHandler) Ping(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -89,12 +121,7 @@ func (h *OllamaHandler) Chat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.Model == "" {
-		config, err := h.configStore.Get("ollama.default_model")
-		if err != nil {
-			req.Model = "qwen2.5:0.5b"
-		} else {
-			req.Model = config.Value
-		}
+		req.Model = h.defaultModel()
 	}
 
 	if req.Message == "" {
@@ -102,17 +129,34 @@ func (h *OllamaHandler) Chat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	systemPrompt := "You are BlueNode Helper, an AI assistant for the BlueNode Server OS."
-	config, err := h.configStore.Get("ollama.system_prompt")
-	if err == nil && config.Value != "" {
-		systemPrompt = config.Value
+	if err := validateOptions(req.Options); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
+	images, err := prepareImages(req.Images)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	systemPrompt := h.systemPrompt()
+
 	var messages []ollama.Message
 	var sessionID string
+	var options database.GenerationOptions
+	// Untitled sessions get a generated title after their first exchange
+	needsTitle := true
 
 	if req.SessionID != "" {
 		sessionID = req.SessionID
+		session, err := h.chatStore.GetSession(sessionID)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		options = session.Options.Merge(req.Options)
+
 		chatMessages, err := h.chatStore.GetMessages(sessionID)
 		if err != nil {
 			log.Printf("Failed to get chat messages: %v", err)
@@ -120,6 +164,14 @@ func (h *OllamaHandler) Chat(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		needsTitle = session.Title == ""
+		for _, msg := range chatMessages {
+			if msg.Role == "user" {
+				needsTitle = false
+				break
+			}
+		}
+
 		if len(chatMessages) == 0 {
 			messages = append(messages, ollama.Message{
 				Role:    "system",
@@ -127,14 +179,12 @@ func (h *OllamaHandler) Chat(w http.ResponseWriter, r *http.Request) {
 			})
 		}
 
-		for _, msg := range chatMessages {
-			messages = append(messages, ollama.Message{
-				Role:    msg.Role,
-				Content: msg.Content,
-			})
-		}
+		pending := estimateTokens(req.Message, len(images))
+		messages = append(messages, h.buildContext(r.Context(), session, req.Model, options.NumCtx, chatMessages, pending)...)
 	} else {
-		session, err := h.chatStore.CreateSession(req.Model, "")
+		options = req.Options
+
+		session, err := h.chatStore.CreateSession(req.Model, "", database.GenerationOptions{})
 		if err != nil {
 			log.Printf("Failed to create chat session: %v", err)
 			writeError(w, http.StatusInternalServerError, err.Error())
@@ -150,27 +200,76 @@ func (h *OllamaHandler) Chat(w http.ResponseWriter, r *http.Request) {
 		h.chatStore.AddMessage(sessionID, "system", systemPrompt)
 	}
 
+	attachments, err := h.storeImages(sessionID, images)
+	if err != nil {
+		log.Printf("Failed to store chat attachments: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	messages = append(messages, ollama.Message{
 		Role:    "user",
 		Content: req.Message,
+		Images:  encodeImages(images),
 	})
 
-	h.chatStore.AddMessage(sessionID, "user", req.Message)
+	userMessage, err := h.chatStore.AddMessageWithAttachments(sessionID, "user", req.Message, attachments)
+	if err != nil {
+		log.Printf("Failed to add chat message: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-	resp, err := h.client.Chat(r.Context(), req.Model, messages)
+	result, answer, err := h.reply(r.Context(), sessionID, userMessage.ID, req.Model, options, messages)
 	if err != nil {
 		log.Printf("Failed to chat with Ollama: %v", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	h.chatStore.AddMessage(sessionID, "assistant", resp.Message.Content)
+	if needsTitle {
+		h.generateTitle(sessionID, req.Message, answer)
+	}
 
-	writeSuccess(w, map[string]interface{}{
+	writeSuccess(w, result)
+}
+
+// reply sends the conversation to the model and stores its answer after the
+// message with ID parentID. The result is the response of the chat
+// endpoints, returned along with the answer itself.
+func (h *OllamaHandler) reply(ctx context.Context, sessionID string, parentID int, model string, options database.GenerationOptions, messages []ollama.Message) (map[string]interface{}, string, error) {
+	resp, toolCalls, pendingActions, err := h.chatWithTools(ctx, sessionID, ollama.ChatRequest{
+		Model:     model,
+		Messages:  messages,
+		Options:   ollamaOptions(options),
+		KeepAlive: options.KeepAlive,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := map[string]interface{}{
 		"session_id": sessionID,
 		"message":    resp.Message.Content,
 		"model":      resp.Model,
-	})
+		"parent_id":  parentID,
+	}
+
+	stored, err := h.chatStore.AddReply(sessionID, parentID, "assistant", resp.Message.Content, nil)
+	if err != nil {
+		log.Printf("Failed to add chat message: %v", err)
+	} else {
+		result["message_id"] = stored.ID
+	}
+
+	if len(toolCalls) > 0 {
+		result["tool_calls"] = toolCalls
+	}
+	if len(pendingActions) > 0 {
+		result["pending_actions"] = pendingActions
+	}
+
+	return result, resp.Message.Content, nil
 }
 
 func (h *OllamaHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
@@ -186,26 +285,22 @@ func (h *OllamaHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.Model == "" {
-		config, err := h.configStore.Get("ollama.default_model")
-		if err != nil {
-			req.Model = "qwen2.5:0.5b"
-		} else {
-			req.Model = config.Value
-		}
+		req.Model = h.defaultModel()
+	}
+
+	if err := validateOptions(req.Options); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	session, err := h.chatStore.CreateSession(req.Model, req.Title)
+	session, err := h.chatStore.CreateSession(req.Model, req.Title, req.Options)
 	if err != nil {
 		log.Printf("Failed to create chat session: %v", err)
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	systemPrompt := "You are BlueNode Helper, an AI assistant for the BlueNode Server OS."
-	config, err := h.configStore.Get("ollama.system_prompt")
-	if err == nil && config.Value != "" {
-		systemPrompt = config.Value
-	}
+	systemPrompt := h.systemPrompt()
 
 	h.chatStore.AddMessage(session.SessionID, "system", systemPrompt)
 
@@ -285,6 +380,7 @@ func (h *OllamaHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusNotFound, err.Error())
 		return
 	}
+	h.removeAttachments(sessionID)
 
 	writeSuccess(w, map[string]string{
 		"status":     "deleted",
@@ -410,12 +506,29 @@ func (h *OllamaHandler) DeleteIndexedFile(w http.ResponseWriter, r *http.Request
 func (h *OllamaHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/ollama/ping", h.Ping)
 	mux.HandleFunc("/ollama/models", h.ListModels)
+	mux.HandleFunc("/ollama/models/pull", h.PullModel)
+	mux.HandleFunc("/ollama/models/delete", h.DeleteModel)
+	mux.HandleFunc("/ollama/models/copy", h.CopyModel)
+	mux.HandleFunc("/ollama/models/show", h.ShowModel)
+	mux.HandleFunc("/ollama/models/running", h.RunningModels)
 	
 	mux.HandleFunc("/ollama/chat", h.Chat)
+	mux.HandleFunc("/ollama/generate", h.Generate)
 	mux.HandleFunc("/ollama/sessions", h.ListSessions)
 	mux.HandleFunc("/ollama/sessions/create", h.CreateSession)
 	mux.HandleFunc("/ollama/sessions/get", h.GetSession)
 	mux.HandleFunc("/ollama/sessions/delete", h.DeleteSession)
+	mux.HandleFunc("/ollama/sessions/rename", h.RenameSession)
+	mux.HandleFunc("/ollama/sessions/export", h.ExportSession)
+	mux.HandleFunc("/ollama/sessions/import", h.ImportSession)
+	mux.HandleFunc("/ollama/sessions/search", h.SearchSessions)
+	mux.HandleFunc("/ollama/sessions/fork", h.ForkSession)
+	mux.HandleFunc("/ollama/sessions/branch", h.SelectBranch)
+	mux.HandleFunc("/ollama/messages/edit", h.EditMessage)
+	mux.HandleFunc("/ollama/messages/regenerate", h.RegenerateMessage)
+	mux.HandleFunc("/ollama/actions", h.ListActions)
+	mux.HandleFunc("/ollama/actions/confirm", h.ConfirmAction)
+	mux.HandleFunc("/ollama/actions/reject", h.RejectAction)
 	
 	mux.HandleFunc("/ollama/files/index", h.IndexFile)
 	mux.HandleFunc("/ollama/files/get", h.GetIndexedFile)
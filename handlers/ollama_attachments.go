@@ -0,0 +1,270 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Image attachments for multimodal chat
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAttachmentsDir = "/var/lib/bnhelper/attachments"
+	maxImageSize          = 20 * 1024 * 1024
+	maxImagesPerMessage   = 4
+)
+
+// imageTypes are the formats vision models accept, with the extension used
+// when storing an upload.
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ImageAttachment is an image sent with a chat message: either a file on the
+// NAS or an upload as base64 data.
+type ImageAttachment struct {
+	Path string `json:"path,omitempty"`
+	Data string `json:"data,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// preparedImage is a validated image waiting to be stored with its message.
+type preparedImage struct {
+	attachment database.Attachment
+	data       []byte
+}
+
+func (h *OllamaHandler) attachmentsDir() string {
+	config, err := h.configStore.Get("ollama.attachments.dir")
+	if err != nil || config.Value == "" {
+		return defaultAttachmentsDir
+	}
+	return config.Value
+}
+
+// prepareImages reads and validates the images of a request before anything
+// is stored, so a bad attachment rejects the whole message.
+func prepareImages(images []ImageAttachment) ([]preparedImage, error) {
+	if len(images) > maxImagesPerMessage {
+		return nil, fmt.Errorf("at most %d images can be sent with a message", maxImagesPerMessage)
+	}
+
+	prepared := make([]preparedImage, 0, len(images))
+	for i, image := range images {
+		var img preparedImage
+		var err error
+		switch {
+		case image.Path != "" && image.Data != "":
+			err = fmt.Errorf("set either path or data, not both")
+		case image.Path != "":
+			img, err = readImageFile(image.Path)
+		case image.Data != "":
+			img, err = decodeImageData(image.Name, image.Data)
+		default:
+			err = fmt.Errorf("path or data is required")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("image %d: %w", i+1, err)
+		}
+		prepared = append(prepared, img)
+	}
+
+	return prepared, nil
+}
+
+func readImageFile(path string) (preparedImage, error) {
+	if !filepath.IsAbs(path) {
+		return preparedImage{}, fmt.Errorf("path must be absolute: %s", path)
+	}
+	path = filepath.Clean(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return preparedImage{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return preparedImage{}, fmt.Errorf("not a regular file: %s", path)
+	}
+	if info.Size() > maxImageSize {
+		return preparedImage{}, fmt.Errorf("%s is larger than %d MB", path, maxImageSize/(1024*1024))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return preparedImage{}, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxImageSize+1))
+	if err != nil {
+		return preparedImage{}, err
+	}
+	if len(data) > maxImageSize {
+		return preparedImage{}, fmt.Errorf("%s is larger than %d MB", path, maxImageSize/(1024*1024))
+	}
+
+	mimeType, err := imageType(data)
+	if err != nil {
+		return preparedImage{}, err
+	}
+
+	return preparedImage{
+		attachment: database.Attachment{
+			Name:     filepath.Base(path),
+			Path:     path,
+			MimeType: mimeType,
+			Size:     int64(len(data)),
+		},
+		data: data,
+	}, nil
+}
+
+func decodeImageData(name, encoded string) (preparedImage, error) {
+	// Accept data URLs as produced by browsers
+	if strings.HasPrefix(encoded, "data:") {
+		if _, rest, found := strings.Cut(encoded, ","); found {
+			encoded = rest
+		}
+	}
+
+	if base64.StdEncoding.DecodedLen(len(encoded)) > maxImageSize+3 {
+		return preparedImage{}, fmt.Errorf("image is larger than %d MB", maxImageSize/(1024*1024))
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return preparedImage{}, fmt.Errorf("invalid base64 data: %w", err)
+	}
+	if len(data) > maxImageSize {
+		return preparedImage{}, fmt.Errorf("image is larger than %d MB", maxImageSize/(1024*1024))
+	}
+
+	mimeType, err := imageType(data)
+	if err != nil {
+		return preparedImage{}, err
+	}
+
+	return preparedImage{
+		attachment: database.Attachment{
+			Name:     filepath.Base(name),
+			MimeType: mimeType,
+			Size:     int64(len(data)),
+			Uploaded: true,
+		},
+		data: data,
+	}, nil
+}
+
+func imageType(data []byte) (string, error) {
+	mimeType := http.DetectContentType(data)
+	if _, ok := imageTypes[mimeType]; !ok {
+		return "", fmt.Errorf("unsupported image type: %s", mimeType)
+	}
+	return mimeType, nil
+}
+
+// storeImages writes uploaded images to the session's attachment directory
+// and returns the references to keep with the message.
+func (h *OllamaHandler) storeImages(sessionID string, images []preparedImage) ([]database.Attachment, error) {
+	attachments := make([]database.Attachment, 0, len(images))
+	for _, img := range images {
+		attachment := img.attachment
+		if attachment.Uploaded {
+			dir := filepath.Join(h.attachmentsDir(), sessionID)
+			if err := os.MkdirAll(dir, 0750); err != nil {
+				return nil, fmt.Errorf("failed to create attachment directory: %w", err)
+			}
+
+			attachment.Path = filepath.Join(dir, uuid.New().String()+imageTypes[attachment.MimeType])
+			if err := os.WriteFile(attachment.Path, img.data, 0640); err != nil {
+				return nil, fmt.Errorf("failed to store attachment: %w", err)
+			}
+			if attachment.Name == "" || attachment.Name == "." || attachment.Name == "/" {
+				attachment.Name = filepath.Base(attachment.Path)
+			}
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+func encodeImages(images []preparedImage) []string {
+	encoded := make([]string, 0, len(images))
+	for _, img := range images {
+		encoded = append(encoded, base64.StdEncoding.EncodeToString(img.data))
+	}
+	return encoded
+}
+
+// loadAttachments encodes the images of an earlier message for replay. Files
+// that were moved or deleted since are left out.
+func loadAttachments(attachments []database.Attachment) []string {
+	var encoded []string
+	for _, attachment := range attachments {
+		// Imported attachments without their data have no path
+		if _, ok := imageTypes[attachment.MimeType]; !ok || attachment.Path == "" {
+			continue
+		}
+		data, err := os.ReadFile(attachment.Path)
+		if err != nil {
+			log.Printf("Failed to read chat attachment %s: %v", attachment.Path, err)
+			continue
+		}
+		// The file may have been replaced since it was attached
+		if _, err := imageType(data); err != nil {
+			log.Printf("Skipping chat attachment %s: %v", attachment.Path, err)
+			continue
+		}
+		encoded = append(encoded, base64.StdEncoding.EncodeToString(data))
+	}
+	return encoded
+}
+
+// copyAttachments stores copies of the uploaded images among attachments for
+// another session. Uploads whose file is gone are kept as a plain reference.
+func (h *OllamaHandler) copyAttachments(sessionID string, attachments []database.Attachment) ([]database.Attachment, error) {
+	var copied []database.Attachment
+	for _, attachment := range attachments {
+		if !attachment.Uploaded {
+			copied = append(copied, attachment)
+			continue
+		}
+
+		data, err := os.ReadFile(attachment.Path)
+		if os.IsNotExist(err) {
+			attachment.Uploaded = false
+			copied = append(copied, attachment)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment %s: %w", attachment.Path, err)
+		}
+
+		stored, err := h.storeImages(sessionID, []preparedImage{{attachment: attachment, data: data}})
+		if err != nil {
+			return nil, err
+		}
+		copied = append(copied, stored...)
+	}
+	return copied, nil
+}
+
+// removeAttachments deletes the files uploaded to a session. Images that
+// were referenced by their NAS path are left alone.
+func (h *OllamaHandler) removeAttachments(sessionID string) {
+	if err := os.RemoveAll(filepath.Join(h.attachmentsDir(), sessionID)); err != nil {
+		log.Printf("Failed to remove attachments of session %s: %v", sessionID, err)
+	}
+}
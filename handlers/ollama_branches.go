@@ -0,0 +1,338 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Editing, regenerating and branching chat messages
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/ollama"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EditMessageRequest struct {
+	SessionID string                     `json:"session_id"`
+	MessageID int                        `json:"message_id"`
+	Message   string                     `json:"message"`
+	Model     string                     `json:"model,omitempty"`
+	Options   database.GenerationOptions `json:"options,omitempty"`
+}
+
+type RegenerateRequest struct {
+	SessionID string                     `json:"session_id"`
+	Model     string                     `json:"model,omitempty"`
+	Options   database.GenerationOptions `json:"options,omitempty"`
+}
+
+type ForkSessionRequest struct {
+	SessionID string `json:"session_id"`
+	MessageID int    `json:"message_id,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+type SelectBranchRequest struct {
+	SessionID string `json:"session_id"`
+	MessageID int    `json:"message_id"`
+}
+
+// EditMessage replaces a user message with a new version and answers it.
+// The original message and everything after it stay in the session as an
+// alternative branch.
+func (h *OllamaHandler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.SessionID == "" {
+		writeError(w, http.StatusBadRequest, "Session ID is required")
+		return
+	}
+	if req.MessageID == 0 {
+		writeError(w, http.StatusBadRequest, "Message ID is required")
+		return
+	}
+	if req.Message == "" {
+		writeError(w, http.StatusBadRequest, "Message is required")
+		return
+	}
+
+	if err := validateOptions(req.Options); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, err := h.chatStore.GetSession(req.SessionID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	original, err := h.chatStore.GetMessage(req.SessionID, req.MessageID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if original.Role != "user" {
+		writeError(w, http.StatusBadRequest, "Only user messages can be edited")
+		return
+	}
+
+	var history []database.ChatMessage
+	if original.ParentID != 0 {
+		history, err = h.chatStore.GetBranch(req.SessionID, original.ParentID)
+		if err != nil {
+			log.Printf("Failed to get chat messages: %v", err)
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	// The edited message keeps the images of the original
+	edited, err := h.chatStore.AddReply(req.SessionID, original.ParentID, "user", req.Message, original.Attachments)
+	if err != nil {
+		log.Printf("Failed to add chat message: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.answer(w, r, session, req.Model, req.Options, history, *edited)
+}
+
+// RegenerateMessage answers the last user message of the current branch
+// again. The previous answer is kept as an alternative.
+func (h *OllamaHandler) RegenerateMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req RegenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.SessionID == "" {
+		writeError(w, http.StatusBadRequest, "Session ID is required")
+		return
+	}
+
+	if err := validateOptions(req.Options); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, err := h.chatStore.GetSession(req.SessionID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	branch, err := h.chatStore.GetMessages(req.SessionID)
+	if err != nil {
+		log.Printf("Failed to get chat messages: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	last := -1
+	for i := len(branch) - 1; i >= 0; i-- {
+		if branch[i].Role == "user" {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		writeError(w, http.StatusBadRequest, "Session has no message to answer")
+		return
+	}
+
+	h.answer(w, r, session, req.Model, req.Options, branch[:last], branch[last])
+}
+
+// answer sends history and the user message to the model and writes the
+// reply, which is stored as a new answer to the user message.
+func (h *OllamaHandler) answer(w http.ResponseWriter, r *http.Request, session *database.ChatSession, model string, override database.GenerationOptions, history []database.ChatMessage, message database.ChatMessage) {
+	if model == "" {
+		model = session.Model
+	}
+	options := session.Options.Merge(override)
+
+	var messages []ollama.Message
+	if len(history) == 0 || history[0].Role != "system" {
+		messages = append(messages, ollama.Message{
+			Role:    "system",
+			Content: h.systemPrompt(),
+		})
+	}
+	messages = append(messages, h.buildContext(r.Context(), session, model, options.NumCtx, history, messageTokens(message))...)
+	messages = append(messages, ollama.Message{
+		Role:    "user",
+		Content: message.Content,
+		Images:  loadAttachments(message.Attachments),
+	})
+
+	result, _, err := h.reply(r.Context(), session.SessionID, message.ID, model, options, messages)
+	if err != nil {
+		log.Printf("Failed to chat with Ollama: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, result)
+}
+
+// ForkSession copies a session up to a message into a new session, which
+// then continues on its own. Without a message ID the whole current branch
+// is copied.
+func (h *OllamaHandler) ForkSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req ForkSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.SessionID == "" {
+		writeError(w, http.StatusBadRequest, "Session ID is required")
+		return
+	}
+	if len([]rune(req.Title)) > maxTitleLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Title must be at most %d characters", maxTitleLength))
+		return
+	}
+
+	session, err := h.chatStore.GetSession(req.SessionID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	var branch []database.ChatMessage
+	if req.MessageID != 0 {
+		branch, err = h.chatStore.GetBranch(req.SessionID, req.MessageID)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+	} else {
+		branch, err = h.chatStore.GetMessages(req.SessionID)
+		if err != nil {
+			log.Printf("Failed to get chat messages: %v", err)
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	title := req.Title
+	if title == "" {
+		title = session.Title
+	}
+
+	fork := database.ChatSession{
+		SessionID: uuid.New().String(),
+		Model:     session.Model,
+		Title:     title,
+		Options:   session.Options,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// Uploaded images are copied so that the fork keeps them when the
+	// original session is deleted
+	for i := range branch {
+		attachments, err := h.copyAttachments(fork.SessionID, branch[i].Attachments)
+		if err != nil {
+			h.removeAttachments(fork.SessionID)
+			log.Printf("Failed to copy chat attachments: %v", err)
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		branch[i].Attachments = attachments
+	}
+
+	created, err := h.chatStore.ImportSession(fork, branch)
+	if err != nil {
+		h.removeAttachments(fork.SessionID)
+		log.Printf("Failed to fork chat session: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	messages, err := h.chatStore.GetMessages(created.SessionID)
+	if err != nil {
+		log.Printf("Failed to get chat messages: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, map[string]interface{}{
+		"session":  created,
+		"messages": messages,
+	})
+}
+
+// SelectBranch switches a session to the branch that contains a message,
+// typically one of the alternatives of a message in the current branch.
+func (h *OllamaHandler) SelectBranch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req SelectBranchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.SessionID == "" {
+		writeError(w, http.StatusBadRequest, "Session ID is required")
+		return
+	}
+	if req.MessageID == 0 {
+		writeError(w, http.StatusBadRequest, "Message ID is required")
+		return
+	}
+
+	if err := h.chatStore.SelectBranch(req.SessionID, req.MessageID); err != nil {
+		log.Printf("Failed to select chat branch: %v", err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	session, err := h.chatStore.GetSession(req.SessionID)
+	if err != nil {
+		log.Printf("Failed to get chat session: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	messages, err := h.chatStore.GetMessages(req.SessionID)
+	if err != nil {
+		log.Printf("Failed to get chat messages: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, map[string]interface{}{
+		"session":  session,
+		"messages": messages,
+	})
+}
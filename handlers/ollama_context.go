@@ -0,0 +1,247 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Fitting long chat sessions into the model's context window
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/ollama"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultContextTokens = 4096
+	defaultKeepMessages  = 6
+	// imageTokens is a rough cost of one image for vision models.
+	imageTokens = 768
+	// maxSummaryMessage caps how much of a single message is given to the
+	// summarizer.
+	maxSummaryMessage = 4000
+)
+
+const summaryPrompt = "You maintain a running summary of a conversation between a user and an AI assistant. " +
+	"Update the summary with the new messages. Keep facts, names, numbers, decisions and open questions " +
+	"the assistant will need later, and drop small talk. Reply with the summary only, in at most 250 words."
+
+type contextSettings struct {
+	enabled      bool
+	maxTokens    int
+	keepMessages int
+	summaryModel string
+}
+
+func (h *OllamaHandler) contextSettings() contextSettings {
+	settings := contextSettings{
+		enabled:      true,
+		maxTokens:    defaultContextTokens,
+		keepMessages: defaultKeepMessages,
+	}
+
+	if config, err := h.configStore.Get("ollama.context.enabled"); err == nil {
+		settings.enabled = config.Value != "false"
+	}
+	if config, err := h.configStore.Get("ollama.context.max_tokens"); err == nil {
+		if n, err := strconv.Atoi(config.Value); err == nil && n > 0 {
+			settings.maxTokens = n
+		}
+	}
+	if config, err := h.configStore.Get("ollama.context.keep_messages"); err == nil {
+		if n, err := strconv.Atoi(config.Value); err == nil && n >= 0 {
+			settings.keepMessages = n
+		}
+	}
+	if config, err := h.configStore.Get("ollama.context.summary_model"); err == nil {
+		settings.summaryModel = config.Value
+	}
+
+	return settings
+}
+
+// estimateTokens approximates the tokens of a message at four characters
+// per token plus a small overhead for the chat template.
+func estimateTokens(content string, images int) int {
+	return len(content)/4 + 4 + images*imageTokens
+}
+
+// buildContext turns the stored history of a session into the messages sent
+// for the next turn. When the history no longer fits, the oldest messages
+// are folded into the session's rolling summary and only the system prompt,
+// the summary and the recent messages are sent. pending is the estimated
+// size of the new user message.
+func (h *OllamaHandler) buildContext(ctx context.Context, session *database.ChatSession, model string, numCtx *int, history []database.ChatMessage, pending int) []ollama.Message {
+	settings := h.contextSettings()
+
+	var system []database.ChatMessage
+	for len(history) > 0 && history[0].Role == "system" {
+		system = append(system, history[0])
+		history = history[1:]
+	}
+
+	if !settings.enabled {
+		return toOllamaMessages(append(system, history...), "")
+	}
+
+	// Messages already in the summary are not sent again. The summary only
+	// applies to the branch it was made on; after an edit further back it
+	// is replaced by one of the new branch.
+	summary := ""
+	for i, msg := range history {
+		if msg.ID == session.SummaryThrough {
+			summary = session.Summary
+			history = history[i+1:]
+			break
+		}
+	}
+
+	budget := settings.maxTokens
+	if numCtx != nil {
+		budget = *numCtx
+	}
+	// Leave a quarter of the window for the reply
+	budget = budget * 3 / 4
+
+	fixed := pending + estimateTokens(summary, 0)
+	for _, msg := range system {
+		fixed += messageTokens(msg)
+	}
+	used := fixed
+	for _, msg := range history {
+		used += messageTokens(msg)
+	}
+	if used <= budget {
+		return toOllamaMessages(append(system, history...), summary)
+	}
+
+	// Keep the most recent messages that fit in half the budget, and never
+	// fewer than keepMessages. The rest is summarized, which leaves room for
+	// a few turns before the next summary.
+	keep := 0
+	recent := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		tokens := messageTokens(history[i])
+		if keep >= settings.keepMessages && fixed+recent+tokens > budget/2 {
+			break
+		}
+		recent += tokens
+		keep++
+	}
+	folded := history[:len(history)-keep]
+	kept := history[len(history)-keep:]
+
+	if len(folded) > 0 {
+		summaryModel := settings.summaryModel
+		if summaryModel == "" {
+			summaryModel = model
+		}
+
+		// On failure the messages not yet summarized are dropped for this
+		// turn and summarized again on the next one.
+		updated, err := h.summarize(ctx, session.SessionID, summaryModel, summary, folded, budget/2)
+		if err != nil {
+			log.Printf("Failed to summarize chat session %s: %v", session.SessionID, err)
+		}
+		summary = updated
+	}
+
+	return toOllamaMessages(append(system, kept...), summary)
+}
+
+// summarize folds messages into the summary in chunks that fit the
+// summarizer's context, saving progress after each chunk.
+func (h *OllamaHandler) summarize(ctx context.Context, sessionID, model, summary string, messages []database.ChatMessage, chunkTokens int) (string, error) {
+	for len(messages) > 0 {
+		var transcript strings.Builder
+		n := 0
+		for n < len(messages) {
+			line := transcriptLine(messages[n])
+			if n > 0 && (transcript.Len()+len(line))/4 > chunkTokens {
+				break
+			}
+			transcript.WriteString(line)
+			n++
+		}
+
+		previous := summary
+		if previous == "" {
+			previous = "(none)"
+		}
+
+		resp, err := h.client.SendChat(ctx, ollama.ChatRequest{
+			Model: model,
+			Messages: []ollama.Message{
+				{Role: "system", Content: summaryPrompt},
+				{Role: "user", Content: fmt.Sprintf("Current summary:\n%s\n\nNew messages:\n%s", previous, transcript.String())},
+			},
+			Options: map[string]interface{}{"temperature": 0.2},
+		})
+		if err != nil {
+			return summary, err
+		}
+
+		updated := strings.TrimSpace(resp.Message.Content)
+		if updated == "" {
+			return summary, fmt.Errorf("summarizer returned an empty summary")
+		}
+		summary = updated
+
+		if err := h.chatStore.UpdateSessionSummary(sessionID, summary, messages[n-1].ID); err != nil {
+			return summary, err
+		}
+		messages = messages[n:]
+	}
+
+	return summary, nil
+}
+
+func transcriptLine(msg database.ChatMessage) string {
+	content := msg.Content
+	if len(content) > maxSummaryMessage {
+		content = content[:maxSummaryMessage] + " [...]"
+	}
+	if len(msg.Attachments) > 0 {
+		content += fmt.Sprintf(" [%d image(s) attached]", len(msg.Attachments))
+	}
+
+	role := msg.Role
+	if role != "" {
+		role = strings.ToUpper(role[:1]) + role[1:]
+	}
+	return role + ": " + content + "\n"
+}
+
+func messageTokens(msg database.ChatMessage) int {
+	return estimateTokens(msg.Content, len(msg.Attachments))
+}
+
+// toOllamaMessages converts stored messages, inserting the summary after the
+// system prompt.
+func toOllamaMessages(history []database.ChatMessage, summary string) []ollama.Message {
+	messages := make([]ollama.Message, 0, len(history)+1)
+	inserted := summary == ""
+	for _, msg := range history {
+		if !inserted && msg.Role != "system" {
+			messages = append(messages, summaryMessage(summary))
+			inserted = true
+		}
+		messages = append(messages, ollama.Message{
+			Role:    msg.Role,
+			Content: msg.Content,
+			Images:  loadAttachments(msg.Attachments),
+		})
+	}
+	if !inserted {
+		messages = append(messages, summaryMessage(summary))
+	}
+	return messages
+}
+
+func summaryMessage(summary string) ollama.Message {
+	return ollama.Message{
+		Role:    "system",
+		Content: "Summary of the earlier conversation:\n" + summary,
+	}
+}
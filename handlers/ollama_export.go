@@ -0,0 +1,379 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Export and import of chat sessions
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	chatExportVersion   = 1
+	maxImportedMessages = 10000
+)
+
+var chatRoles = map[string]bool{
+	"system":    true,
+	"user":      true,
+	"assistant": true,
+	"tool":      true,
+}
+
+// ChatExport is the JSON export of a session. It can be imported on any
+// helper as is.
+type ChatExport struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Session    ExportedSession   `json:"session"`
+	Messages   []ExportedMessage `json:"messages"`
+}
+
+type ExportedSession struct {
+	SessionID string                     `json:"session_id"`
+	Model     string                     `json:"model"`
+	Title     string                     `json:"title,omitempty"`
+	Options   database.GenerationOptions `json:"options"`
+	// HeadID is the last message of the current branch.
+	HeadID    int       `json:"head_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExportedMessage is a message of an export. ID and ParentID link the
+// messages into branches; they are only meaningful within the export.
+type ExportedMessage struct {
+	ID          int                  `json:"id,omitempty"`
+	ParentID    int                  `json:"parent_id,omitempty"`
+	Role        string               `json:"role"`
+	Content     string               `json:"content"`
+	Attachments []ExportedAttachment `json:"attachments,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+}
+
+// ExportedAttachment carries the image itself in Data when uploaded images
+// are included in the export.
+type ExportedAttachment struct {
+	database.Attachment
+	Data string `json:"data,omitempty"`
+}
+
+// ExportSession writes a session as a JSON or Markdown document. The
+// document is the response body, not wrapped in the usual envelope, so it
+// can be saved as a file and imported unchanged.
+func (h *OllamaHandler) ExportSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	sessionID := query.Get("session_id")
+	if sessionID == "" {
+		writeError(w, http.StatusBadRequest, "Session ID is required")
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "markdown" {
+		writeError(w, http.StatusBadRequest, `Format must be "json" or "markdown"`)
+		return
+	}
+
+	session, err := h.chatStore.GetSession(sessionID)
+	if err != nil {
+		log.Printf("Failed to get chat session: %v", err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	// The Markdown transcript shows the current branch, the JSON export
+	// keeps all of them
+	messages, err := h.chatStore.GetMessages(sessionID)
+	if err != nil {
+		log.Printf("Failed to get chat messages: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if format == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chat-%s.md"`, sessionID))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(chatMarkdown(session, messages)))
+		return
+	}
+
+	var headID int
+	if len(messages) > 0 {
+		headID = messages[len(messages)-1].ID
+	}
+
+	all, err := h.chatStore.GetAllMessages(sessionID)
+	if err != nil {
+		log.Printf("Failed to get chat messages: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	export, err := exportSession(session, all, headID, query.Get("images") == "true")
+	if err != nil {
+		log.Printf("Failed to export chat session: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chat-%s.json"`, sessionID))
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(export)
+}
+
+func exportSession(session *database.ChatSession, messages []database.ChatMessage, headID int, includeImages bool) (*ChatExport, error) {
+	export := &ChatExport{
+		Version:    chatExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Session: ExportedSession{
+			SessionID: session.SessionID,
+			Model:     session.Model,
+			Title:     session.Title,
+			Options:   session.Options,
+			HeadID:    headID,
+			CreatedAt: session.CreatedAt,
+			UpdatedAt: session.UpdatedAt,
+		},
+		Messages: make([]ExportedMessage, 0, len(messages)),
+	}
+
+	for _, msg := range messages {
+		exported := ExportedMessage{
+			ID:        msg.ID,
+			ParentID:  msg.ParentID,
+			Role:      msg.Role,
+			Content:   msg.Content,
+			CreatedAt: msg.CreatedAt,
+		}
+		for _, attachment := range msg.Attachments {
+			item := ExportedAttachment{Attachment: attachment}
+			if includeImages && attachment.Uploaded {
+				data, err := os.ReadFile(attachment.Path)
+				if err != nil && !os.IsNotExist(err) {
+					return nil, fmt.Errorf("failed to read attachment %s: %w", attachment.Path, err)
+				}
+				if err == nil {
+					item.Data = base64.StdEncoding.EncodeToString(data)
+				}
+			}
+			exported.Attachments = append(exported.Attachments, item)
+		}
+		export.Messages = append(export.Messages, exported)
+	}
+
+	return export, nil
+}
+
+func chatMarkdown(session *database.ChatSession, messages []database.ChatMessage) string {
+	var b strings.Builder
+
+	title := session.Title
+	if title == "" {
+		title = "Chat session"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- Model: `%s`\n", session.Model)
+	fmt.Fprintf(&b, "- Session: `%s`\n", session.SessionID)
+	fmt.Fprintf(&b, "- Created: %s\n", session.CreatedAt.UTC().Format("2006-01-02 15:04:05 UTC"))
+
+	for _, msg := range messages {
+		role := msg.Role
+		if role != "" {
+			role = strings.ToUpper(role[:1]) + role[1:]
+		}
+		fmt.Fprintf(&b, "\n---\n\n## %s\n\n*%s*\n\n", role, msg.CreatedAt.UTC().Format("2006-01-02 15:04:05 UTC"))
+		b.WriteString(strings.TrimSpace(msg.Content))
+		b.WriteString("\n")
+
+		if len(msg.Attachments) > 0 {
+			b.WriteString("\nAttachments:\n")
+			for _, attachment := range msg.Attachments {
+				if attachment.Path == "" {
+					fmt.Fprintf(&b, "- %s (unavailable)\n", attachment.Name)
+					continue
+				}
+				fmt.Fprintf(&b, "- %s (`%s`)\n", attachment.Name, attachment.Path)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// ImportSession recreates a session from a JSON export under a new session
+// ID. Uploaded images included in the export are stored again; other
+// attachments only keep their name, without a path, and are not sent to
+// the model. Exports without message IDs are imported as a single branch.
+func (h *OllamaHandler) ImportSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var export ChatExport
+	if err := json.NewDecoder(r.Body).Decode(&export); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validateExport(&export); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session := database.ChatSession{
+		SessionID: uuid.New().String(),
+		Model:     export.Session.Model,
+		Title:     export.Session.Title,
+		Options:   export.Session.Options,
+		CreatedAt: export.Session.CreatedAt,
+		UpdatedAt: export.Session.UpdatedAt,
+	}
+
+	messages := make([]database.ChatMessage, 0, len(export.Messages))
+	for i, msg := range headLast(export.Messages, export.Session.HeadID) {
+		attachments, err := h.importAttachments(session.SessionID, msg.Attachments)
+		if err != nil {
+			h.removeAttachments(session.SessionID)
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("message %d: %v", i+1, err))
+			return
+		}
+		messages = append(messages, database.ChatMessage{
+			ID:          msg.ID,
+			ParentID:    msg.ParentID,
+			Role:        msg.Role,
+			Content:     msg.Content,
+			Attachments: attachments,
+			CreatedAt:   msg.CreatedAt,
+		})
+	}
+
+	imported, err := h.chatStore.ImportSession(session, messages)
+	if err != nil {
+		h.removeAttachments(session.SessionID)
+		log.Printf("Failed to import chat session: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, map[string]interface{}{
+		"session":  imported,
+		"messages": len(messages),
+	})
+}
+
+func validateExport(export *ChatExport) error {
+	if export.Version != chatExportVersion {
+		return fmt.Errorf("unsupported export version: %d", export.Version)
+	}
+	if export.Session.Model == "" {
+		return fmt.Errorf("session model is required")
+	}
+	if len([]rune(export.Session.Title)) > maxTitleLength {
+		return fmt.Errorf("title must be at most %d characters", maxTitleLength)
+	}
+	if err := validateOptions(export.Session.Options); err != nil {
+		return err
+	}
+	if len(export.Messages) > maxImportedMessages {
+		return fmt.Errorf("at most %d messages can be imported", maxImportedMessages)
+	}
+
+	ids := make(map[int]bool, len(export.Messages))
+	for i, msg := range export.Messages {
+		if !chatRoles[msg.Role] {
+			return fmt.Errorf("message %d: invalid role: %q", i+1, msg.Role)
+		}
+		if (msg.ID == 0) != (export.Messages[0].ID == 0) {
+			return fmt.Errorf("message %d: either all messages or none must have an id", i+1)
+		}
+		if msg.ID != 0 && ids[msg.ID] {
+			return fmt.Errorf("message %d: duplicate id %d", i+1, msg.ID)
+		}
+		if msg.ParentID != 0 && !ids[msg.ParentID] {
+			return fmt.Errorf("message %d: parent %d must come before the message", i+1, msg.ParentID)
+		}
+		ids[msg.ID] = true
+	}
+	if id := export.Session.HeadID; id != 0 {
+		if !ids[id] {
+			return fmt.Errorf("head message %d not found", id)
+		}
+		for _, msg := range export.Messages {
+			if msg.ParentID == id {
+				return fmt.Errorf("head message %d must be the last of its branch", id)
+			}
+		}
+	}
+	return nil
+}
+
+// headLast moves the head message to the end. The newest message becomes
+// the head of an imported session, and as the head has no replies it can
+// be stored last without breaking the order of any branch.
+func headLast(messages []ExportedMessage, headID int) []ExportedMessage {
+	if headID == 0 {
+		return messages
+	}
+	ordered := make([]ExportedMessage, 0, len(messages))
+	var head []ExportedMessage
+	for _, msg := range messages {
+		if msg.ID == headID {
+			head = append(head, msg)
+			continue
+		}
+		ordered = append(ordered, msg)
+	}
+	return append(ordered, head...)
+}
+
+// importAttachments stores the images embedded in an export for the new
+// session.
+func (h *OllamaHandler) importAttachments(sessionID string, exported []ExportedAttachment) ([]database.Attachment, error) {
+	var attachments []database.Attachment
+	for _, item := range exported {
+		attachment := item.Attachment
+		if item.Data == "" {
+			// Without its data the attachment refers to a file on the
+			// machine it was exported from. Only the name is kept, so the
+			// import cannot point the model at files on this one.
+			attachment.Uploaded = false
+			attachment.Path = ""
+			attachments = append(attachments, attachment)
+			continue
+		}
+
+		img, err := decodeImageData(attachment.Name, item.Data)
+		if err != nil {
+			return nil, err
+		}
+		stored, err := h.storeImages(sessionID, []preparedImage{img})
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, stored...)
+	}
+	return attachments, nil
+}
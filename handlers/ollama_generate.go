@@ -0,0 +1,121 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handler for one-shot Ollama completions
+
+package handlers
+
+import (
+	"bluenode-helper/ollama"
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+type GenerateRequest struct {
+	Model   string                 `json:"model"`
+	Prompt  string                 `json:"prompt"`
+	System  string                 `json:"system,omitempty"`
+	Options map[string]interface{} `json:"options,omitempty"`
+	// Format is "json" for any JSON document, or a JSON schema the response
+	// is constrained to and validated against.
+	Format    json.RawMessage `json:"format,omitempty"`
+	KeepAlive string          `json:"keep_alive,omitempty"`
+}
+
+type GenerateResult struct {
+	Model           string      `json:"model"`
+	Response        string      `json:"response"`
+	JSON            interface{} `json:"json,omitempty"`
+	SchemaErrors    []string    `json:"schema_errors,omitempty"`
+	DoneReason      string      `json:"done_reason,omitempty"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+	EvalCount       int         `json:"eval_count"`
+	TotalDuration   int64       `json:"total_duration"`
+}
+
+// Generate runs a single completion that is not stored in any chat session.
+// Structured output is only returned once it parses and matches the schema.
+func (h *OllamaHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req GenerateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Prompt == "" {
+		writeError(w, http.StatusBadRequest, "Prompt is required")
+		return
+	}
+
+	if req.Model == "" {
+		req.Model = h.defaultModel()
+	}
+
+	var schema *ollama.Schema
+	if format := bytes.TrimSpace(req.Format); len(format) > 0 && !bytes.Equal(format, []byte("null")) {
+		switch format[0] {
+		case '"':
+			var name string
+			if err := json.Unmarshal(format, &name); err != nil || name != "json" {
+				writeError(w, http.StatusBadRequest, `Format must be "json" or a JSON schema object`)
+				return
+			}
+			// An empty schema accepts any JSON document
+			schema, _ = ollama.ParseSchema(json.RawMessage(`{}`))
+		case '{':
+			parsed, err := ollama.ParseSchema(json.RawMessage(format))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			schema = parsed
+		default:
+			writeError(w, http.StatusBadRequest, `Format must be "json" or a JSON schema object`)
+			return
+		}
+	}
+
+	resp, err := h.client.Generate(r.Context(), ollama.GenerateRequest{
+		Model:     req.Model,
+		Prompt:    req.Prompt,
+		System:    req.System,
+		Format:    req.Format,
+		Options:   req.Options,
+		KeepAlive: req.KeepAlive,
+	})
+	if err != nil {
+		log.Printf("Failed to generate with Ollama: %v", err)
+		writeOllamaError(w, err)
+		return
+	}
+
+	result := GenerateResult{
+		Model:           resp.Model,
+		Response:        resp.Response,
+		DoneReason:      resp.DoneReason,
+		PromptEvalCount: resp.PromptEvalCount,
+		EvalCount:       resp.EvalCount,
+		TotalDuration:   resp.TotalDuration,
+	}
+
+	if schema != nil {
+		value, violations := schema.Validate([]byte(resp.Response))
+		if len(violations) > 0 {
+			result.SchemaErrors = violations
+			writeJSON(w, http.StatusUnprocessableEntity, APIResponse{
+				Success: false,
+				Error:   "Model response does not match the requested format",
+				Data:    result,
+			})
+			return
+		}
+		result.JSON = value
+	}
+
+	writeSuccess(w, result)
+}
@@ -0,0 +1,171 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handlers for Ollama model management endpoints
+
+package handlers
+
+import (
+	"bluenode-helper/ollama"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+type PullModelRequest struct {
+	Model string `json:"model"`
+}
+
+type CopyModelRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// PullModel downloads a model. With ?stream=true every progress update from
+// Ollama is sent as it arrives.
+func (h *OllamaHandler) PullModel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req PullModelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Model == "" {
+		writeError(w, http.StatusBadRequest, "Model is required")
+		return
+	}
+
+	result := map[string]string{"status": "pulled", "model": req.Model}
+
+	if wantsStream(r) {
+		stream := newStreamWriter(w)
+		err := h.client.PullModel(r.Context(), req.Model, func(progress ollama.PullProgress) {
+			stream.send(progress)
+		})
+		if err != nil {
+			log.Printf("Failed to pull model %s: %v", req.Model, err)
+			stream.fail(err.Error(), nil)
+			return
+		}
+		stream.success(result)
+		return
+	}
+
+	if err := h.client.PullModel(r.Context(), req.Model, nil); err != nil {
+		log.Printf("Failed to pull model %s: %v", req.Model, err)
+		writeOllamaError(w, err)
+		return
+	}
+
+	writeSuccess(w, result)
+}
+
+func (h *OllamaHandler) DeleteModel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	model := r.URL.Query().Get("model")
+	if model == "" {
+		writeError(w, http.StatusBadRequest, "Model is required")
+		return
+	}
+
+	if err := h.client.DeleteModel(r.Context(), model); err != nil {
+		log.Printf("Failed to delete model %s: %v", model, err)
+		writeOllamaError(w, err)
+		return
+	}
+
+	writeSuccess(w, map[string]string{
+		"status": "deleted",
+		"model":  model,
+	})
+}
+
+func (h *OllamaHandler) CopyModel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req CopyModelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Source == "" || req.Destination == "" {
+		writeError(w, http.StatusBadRequest, "Source and destination are required")
+		return
+	}
+
+	if err := h.client.CopyModel(r.Context(), req.Source, req.Destination); err != nil {
+		log.Printf("Failed to copy model %s to %s: %v", req.Source, req.Destination, err)
+		writeOllamaError(w, err)
+		return
+	}
+
+	writeSuccess(w, map[string]string{
+		"status":      "copied",
+		"source":      req.Source,
+		"destination": req.Destination,
+	})
+}
+
+func (h *OllamaHandler) ShowModel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	model := r.URL.Query().Get("model")
+	if model == "" {
+		writeError(w, http.StatusBadRequest, "Model is required")
+		return
+	}
+
+	info, err := h.client.ShowModel(r.Context(), model)
+	if err != nil {
+		log.Printf("Failed to show model %s: %v", model, err)
+		writeOllamaError(w, err)
+		return
+	}
+
+	writeSuccess(w, info)
+}
+
+func (h *OllamaHandler) RunningModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	models, err := h.client.RunningModels(r.Context())
+	if err != nil {
+		log.Printf("Failed to list running Ollama models: %v", err)
+		writeOllamaError(w, err)
+		return
+	}
+
+	writeSuccess(w, models)
+}
+
+// writeOllamaError passes on Ollama's not found and bad request statuses, so
+// a typo in a model name is not reported as a server error.
+func writeOllamaError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *ollama.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusNotFound, http.StatusBadRequest:
+			status = apiErr.StatusCode
+		}
+	}
+	writeError(w, status, err.Error())
+}
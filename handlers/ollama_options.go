@@ -0,0 +1,63 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Generation options for chat sessions
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"fmt"
+	"time"
+)
+
+const maxStopSequences = 8
+
+func validateOptions(options database.GenerationOptions) error {
+	if t := options.Temperature; t != nil && (*t < 0 || *t > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if p := options.TopP; p != nil && (*p < 0 || *p > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1")
+	}
+	if n := options.NumCtx; n != nil && *n < 1 {
+		return fmt.Errorf("num_ctx must be positive")
+	}
+	if len(options.Stop) > maxStopSequences {
+		return fmt.Errorf("at most %d stop sequences are allowed", maxStopSequences)
+	}
+	for _, stop := range options.Stop {
+		if stop == "" {
+			return fmt.Errorf("stop sequences must not be empty")
+		}
+	}
+	if options.KeepAlive != "" {
+		if _, err := time.ParseDuration(options.KeepAlive); err != nil {
+			return fmt.Errorf("keep_alive must be a duration such as 5m or 1h")
+		}
+	}
+	return nil
+}
+
+// ollamaOptions converts the options to the model parameters of an Ollama
+// request. keep_alive is a separate request field and is not included.
+func ollamaOptions(options database.GenerationOptions) map[string]interface{} {
+	params := make(map[string]interface{})
+	if options.Temperature != nil {
+		params["temperature"] = *options.Temperature
+	}
+	if options.NumCtx != nil {
+		params["num_ctx"] = *options.NumCtx
+	}
+	if options.TopP != nil {
+		params["top_p"] = *options.TopP
+	}
+	if options.Seed != nil {
+		params["seed"] = *options.Seed
+	}
+	if len(options.Stop) > 0 {
+		params["stop"] = options.Stop
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
@@ -0,0 +1,89 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Search across chat sessions
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SearchSessions finds messages across all chat sessions, newest first.
+func (h *OllamaHandler) SearchSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	q := database.ChatSearchQuery{
+		Text:  query.Get("q"),
+		Model: query.Get("model"),
+		Role:  query.Get("role"),
+	}
+
+	if strings.TrimSpace(q.Text) == "" {
+		writeError(w, http.StatusBadRequest, "Query is required")
+		return
+	}
+
+	if q.Role != "" && (q.Role == "system" || !chatRoles[q.Role]) {
+		writeError(w, http.StatusBadRequest, "Role must be user, assistant or tool")
+		return
+	}
+
+	if v := query.Get("to"); v != "" {
+		parsed, err := parseMetricsTime(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		q.To = parsed
+	}
+	if v := query.Get("range"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "Invalid range, use a duration such as 720h")
+			return
+		}
+		to := q.To
+		if to.IsZero() {
+			to = time.Now()
+		}
+		q.From = to.Add(-d)
+	}
+	if v := query.Get("from"); v != "" {
+		parsed, err := parseMetricsTime(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		q.From = parsed
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		writeError(w, http.StatusBadRequest, "from must be before to")
+		return
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > database.MaxChatSearchLimit {
+			writeError(w, http.StatusBadRequest, "Limit must be between 1 and "+strconv.Itoa(database.MaxChatSearchLimit))
+			return
+		}
+		q.Limit = limit
+	}
+
+	results, err := h.chatStore.Search(q)
+	if err != nil {
+		log.Printf("Failed to search chat sessions: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, results)
+}
@@ -0,0 +1,136 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Chat session titles, generated or set by the user
+
+package handlers
+
+import (
+	"bluenode-helper/ollama"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxTitleLength = 200
+	// maxGeneratedTitle keeps generated titles short enough for a list.
+	maxGeneratedTitle = 60
+	titleTimeout      = 2 * time.Minute
+	// maxTitleExcerpt limits how much of the first exchange the model reads.
+	maxTitleExcerpt = 1500
+)
+
+const titleSystemPrompt = "You write titles for chat conversations. Reply with a title of at most six words " +
+	"that describes the topic. No quotes, no punctuation at the end, nothing else."
+
+type RenameSessionRequest struct {
+	SessionID string `json:"session_id"`
+	Title     string `json:"title"`
+}
+
+func (h *OllamaHandler) titlesEnabled() bool {
+	config, err := h.configStore.Get("ollama.titles.enabled")
+	return err != nil || config.Value != "false"
+}
+
+// generateTitle names a session after its first exchange. It runs in the
+// background so the reply is not delayed, and leaves the session alone if it
+// was given a title in the meantime.
+func (h *OllamaHandler) generateTitle(sessionID, message, reply string) {
+	if !h.titlesEnabled() {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(h.ctx, titleTimeout)
+		defer cancel()
+
+		resp, err := h.client.Generate(ctx, ollama.GenerateRequest{
+			Model:   h.defaultModel(),
+			System:  titleSystemPrompt,
+			Prompt:  fmt.Sprintf("User: %s\nAssistant: %s\n\nTitle:", truncate(message, maxTitleExcerpt), truncate(reply, maxTitleExcerpt)),
+			Options: map[string]interface{}{"temperature": 0.3, "num_predict": 24},
+		})
+		if err != nil {
+			log.Printf("Failed to generate title for chat session %s: %v", sessionID, err)
+			return
+		}
+
+		title := cleanTitle(resp.Response)
+		if title == "" {
+			return
+		}
+
+		if err := h.chatStore.SetGeneratedTitle(sessionID, title); err != nil {
+			log.Printf("Failed to save title for chat session %s: %v", sessionID, err)
+		}
+	}()
+}
+
+// cleanTitle takes the first line of the model's answer and strips the
+// quoting and labels small models tend to add.
+func cleanTitle(text string) string {
+	title := strings.TrimSpace(text)
+	if line, _, found := strings.Cut(title, "\n"); found {
+		title = line
+	}
+	title = strings.TrimPrefix(title, "Title:")
+	title = strings.Trim(title, " \t*#\"'`“”")
+	title = strings.TrimRight(title, ".!:;,")
+	return truncate(strings.TrimSpace(title), maxGeneratedTitle)
+}
+
+// truncate shortens s to at most n characters without splitting a rune.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func (h *OllamaHandler) RenameSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req RenameSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.SessionID == "" {
+		writeError(w, http.StatusBadRequest, "Session ID is required")
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		writeError(w, http.StatusBadRequest, "Title is required")
+		return
+	}
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Title must be at most %d characters", maxTitleLength))
+		return
+	}
+
+	if err := h.chatStore.UpdateSessionTitle(req.SessionID, title); err != nil {
+		log.Printf("Failed to rename chat session: %v", err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	session, err := h.chatStore.GetSession(req.SessionID)
+	if err != nil {
+		log.Printf("Failed to get chat session: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, session)
+}
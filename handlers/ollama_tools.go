@@ -0,0 +1,269 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Tool calling in chat and confirmation of assistant actions
+
+package handlers
+
+import (
+	"bluenode-helper/assistant"
+	"bluenode-helper/database"
+	"bluenode-helper/ollama"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxToolRounds bounds how often the model may call tools before it has
+	// to answer.
+	maxToolRounds = 5
+	// actionTTL is how long a requested action can wait for confirmation.
+	actionTTL = 15 * time.Minute
+)
+
+// ToolCallResult reports a tool call the model made while answering.
+type ToolCallResult struct {
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Status    string                 `json:"status"`
+	ActionID  string                 `json:"action_id,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+type ActionRequest struct {
+	ActionID string `json:"action_id"`
+}
+
+func (h *OllamaHandler) toolsEnabled() bool {
+	config, err := h.configStore.Get("ollama.tools.enabled")
+	return err != nil || config.Value != "false"
+}
+
+// chatWithTools sends the conversation to the model and runs the tools it
+// calls until it answers. Mutating tools are not run; they are queued as
+// pending actions for the user to confirm.
+func (h *OllamaHandler) chatWithTools(ctx context.Context, sessionID string, req ollama.ChatRequest) (*ollama.ChatResponse, []ToolCallResult, []database.PendingAction, error) {
+	if h.toolsEnabled() {
+		req.Tools = h.toolbox.Definitions()
+	}
+
+	calls := []ToolCallResult{}
+	pending := []database.PendingAction{}
+	for round := 0; ; round++ {
+		if round == maxToolRounds {
+			req.Tools = nil
+		}
+
+		resp, err := h.client.SendChat(ctx, req)
+		if err != nil && req.Tools != nil && ollama.IsToolsUnsupported(err) {
+			req.Tools = nil
+			resp, err = h.client.SendChat(ctx, req)
+		}
+		if err != nil {
+			return nil, calls, pending, err
+		}
+
+		if len(resp.Message.ToolCalls) == 0 || req.Tools == nil {
+			return resp, calls, pending, nil
+		}
+
+		req.Messages = append(req.Messages, resp.Message)
+		for _, call := range resp.Message.ToolCalls {
+			result, output, action := h.runToolCall(ctx, sessionID, call)
+			calls = append(calls, result)
+			if action != nil {
+				pending = append(pending, *action)
+			}
+			req.Messages = append(req.Messages, ollama.Message{
+				Role:     "tool",
+				Content:  output,
+				ToolName: call.Function.Name,
+			})
+		}
+	}
+}
+
+// runToolCall runs a read-only tool or queues a mutating one, and returns
+// the text given back to the model.
+func (h *OllamaHandler) runToolCall(ctx context.Context, sessionID string, call ollama.ToolCall) (ToolCallResult, string, *database.PendingAction) {
+	result := ToolCallResult{
+		Tool:      call.Function.Name,
+		Arguments: call.Function.Arguments,
+		Status:    database.ActionFailed,
+	}
+	if result.Arguments == nil {
+		result.Arguments = map[string]interface{}{}
+	}
+
+	tool, ok := h.toolbox.Lookup(call.Function.Name)
+	if !ok {
+		result.Error = "unknown tool: " + call.Function.Name
+		return result, "error: " + result.Error, nil
+	}
+
+	if err := tool.Check(result.Arguments); err != nil {
+		result.Error = err.Error()
+		return result, "error: " + result.Error, nil
+	}
+
+	if tool.Mutating {
+		action, err := h.actionStore.Create(sessionID, tool.Name, result.Arguments)
+		if err != nil {
+			log.Printf("Failed to create pending action: %v", err)
+			result.Error = err.Error()
+			return result, "error: " + result.Error, nil
+		}
+		result.Status = database.ActionPending
+		result.ActionID = action.ActionID
+		output := fmt.Sprintf("Action %s (%s) has NOT run yet. It is waiting for the user to confirm it. Tell the user it needs their confirmation.", action.ActionID, tool.Describe(result.Arguments))
+		return result, output, action
+	}
+
+	output, err := tool.Run(ctx, result.Arguments)
+	if err != nil {
+		log.Printf("Assistant tool %s failed: %v", tool.Name, err)
+		result.Error = err.Error()
+		return result, "error: " + result.Error, nil
+	}
+
+	result.Status = database.ActionExecuted
+	return result, output, nil
+}
+
+func (h *OllamaHandler) ListActions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	actions, err := h.actionStore.List(query.Get("session_id"), query.Get("status"))
+	if err != nil {
+		log.Printf("Failed to list actions: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, actions)
+}
+
+// ConfirmAction runs an action the assistant requested. The result is also
+// added to the chat session so the model sees it in the next turn.
+func (h *OllamaHandler) ConfirmAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	action, ok := h.claimAction(w, r, database.ActionRunning)
+	if !ok {
+		return
+	}
+
+	status := database.ActionExecuted
+	var output string
+	tool, found := h.toolbox.Lookup(action.Tool)
+	if !found {
+		status = database.ActionFailed
+		output = "unknown tool: " + action.Tool
+	} else {
+		var err error
+		output, err = tool.Run(r.Context(), action.Arguments)
+		if err != nil {
+			log.Printf("Failed to run action %s: %v", action.ActionID, err)
+			status = database.ActionFailed
+			output = err.Error()
+		}
+	}
+
+	if err := h.actionStore.Finish(action.ActionID, status, output); err != nil {
+		log.Printf("Failed to record action result: %v", err)
+	}
+	h.chatStore.AddMessage(action.SessionID, "tool", fmt.Sprintf("Action %s (%s) was confirmed by the user and %s: %s", action.ActionID, describeAction(tool, action), status, output))
+
+	h.writeAction(w, action.ActionID)
+}
+
+func (h *OllamaHandler) RejectAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	action, ok := h.claimAction(w, r, database.ActionRejected)
+	if !ok {
+		return
+	}
+
+	tool, _ := h.toolbox.Lookup(action.Tool)
+	h.chatStore.AddMessage(action.SessionID, "tool", fmt.Sprintf("Action %s (%s) was rejected by the user and did not run.", action.ActionID, describeAction(tool, action)))
+
+	h.writeAction(w, action.ActionID)
+}
+
+// claimAction decodes the request and moves its pending action to status.
+// It writes the error response itself and reports false when the action
+// cannot be claimed.
+func (h *OllamaHandler) claimAction(w http.ResponseWriter, r *http.Request, status string) (*database.PendingAction, bool) {
+	var req ActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+
+	if req.ActionID == "" {
+		writeError(w, http.StatusBadRequest, "Action ID is required")
+		return nil, false
+	}
+
+	action, err := h.actionStore.Get(req.ActionID)
+	if err != nil {
+		log.Printf("Failed to get action: %v", err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+
+	if action.Status == database.ActionPending && time.Since(action.CreatedAt) > actionTTL {
+		if _, err := h.actionStore.Resolve(action.ActionID, database.ActionExpired); err != nil {
+			log.Printf("Failed to expire action: %v", err)
+		}
+		writeError(w, http.StatusConflict, "Action has expired")
+		return nil, false
+	}
+
+	claimed, err := h.actionStore.Resolve(action.ActionID, status)
+	if err != nil {
+		log.Printf("Failed to update action: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if !claimed {
+		if current, err := h.actionStore.Get(action.ActionID); err == nil {
+			action = current
+		}
+		writeError(w, http.StatusConflict, fmt.Sprintf("Action is already %s", action.Status))
+		return nil, false
+	}
+
+	return action, true
+}
+
+func (h *OllamaHandler) writeAction(w http.ResponseWriter, actionID string) {
+	action, err := h.actionStore.Get(actionID)
+	if err != nil {
+		log.Printf("Failed to get action: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, action)
+}
+
+func describeAction(tool *assistant.Tool, action *database.PendingAction) string {
+	if tool == nil {
+		return action.Tool
+	}
+	return tool.Describe(action.Arguments)
+}
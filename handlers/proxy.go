@@ -0,0 +1,49 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handlers for the container web proxy
+
+package handlers
+
+import (
+	"bluenode-helper/proxy"
+	"log"
+	"net/http"
+)
+
+type ProxyHandler struct {
+	proxy *proxy.Proxy
+}
+
+func NewProxyHandler(p *proxy.Proxy) *ProxyHandler {
+	return &ProxyHandler{
+		proxy: p,
+	}
+}
+
+func (h *ProxyHandler) ListRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	writeSuccess(w, h.proxy.Routes())
+}
+
+func (h *ProxyHandler) RefreshRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if err := h.proxy.Refresh(r.Context()); err != nil {
+		log.Printf("Failed to refresh web proxy routes: %v", err)
+		writeDockerError(w, err)
+		return
+	}
+
+	writeSuccess(w, h.proxy.Routes())
+}
+
+func (h *ProxyHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/proxy/routes", h.ListRoutes)
+	mux.HandleFunc("/proxy/refresh", h.RefreshRoutes)
+}
@@ -0,0 +1,127 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handlers for private registry credential endpoints
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/docker"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+type SetRegistryRequest struct {
+	Registry string `json:"registry"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+func (h *DockerHandler) ListRegistries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	creds, err := h.registries.List()
+	if err != nil {
+		log.Printf("Failed to list registry credentials: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, creds)
+}
+
+func (h *DockerHandler) SetRegistry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req SetRegistryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Registry == "" || req.Username == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "Registry, username and password are required")
+		return
+	}
+
+	registry := docker.NormalizeRegistryHost(req.Registry)
+
+	// Verify the credentials with the daemon before storing them
+	if _, err := h.client.Auth(r.Context(), &docker.AuthConfig{
+		Username:      req.Username,
+		Password:      req.Password,
+		ServerAddress: registry,
+	}); err != nil {
+		log.Printf("Registry login to %s failed: %v", registry, err)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.registries.Set(registry, req.Username, req.Password); err != nil {
+		log.Printf("Failed to store registry credential for %s: %v", registry, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, map[string]string{
+		"status":   "saved",
+		"registry": registry,
+	})
+}
+
+func (h *DockerHandler) DeleteRegistry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	registry := r.URL.Query().Get("registry")
+	if registry == "" {
+		writeError(w, http.StatusBadRequest, "Registry is required")
+		return
+	}
+	registry = docker.NormalizeRegistryHost(registry)
+
+	if err := h.registries.Delete(registry); err != nil {
+		log.Printf("Failed to delete registry credential for %s: %v", registry, err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeSuccess(w, map[string]string{
+		"status":   "deleted",
+		"registry": registry,
+	})
+}
+
+// authFor returns the stored credentials for the registry an image is pulled
+// from, or nil when none are stored. A credential that cannot be read, for
+// example because the secret key changed, is an error rather than an
+// anonymous pull.
+func (h *DockerHandler) authFor(image string) (*docker.AuthConfig, error) {
+	if h.registries == nil {
+		return nil, nil
+	}
+
+	registry := docker.RegistryHost(image)
+	cred, err := h.registries.Get(registry)
+	if errors.Is(err, database.ErrCredentialNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &docker.AuthConfig{
+		Username:      cred.Username,
+		Password:      cred.Password,
+		ServerAddress: registry,
+	}, nil
+}
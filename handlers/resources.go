@@ -0,0 +1,214 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handlers for live container resource limit updates
+
+package handlers
+
+import (
+	"bluenode-helper/docker"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Docker rejects memory limits below 6MB
+	minMemoryLimit   = 6 * 1024 * 1024
+	defaultCPUPeriod = 100000
+	minCPUQuota      = 1000
+	minCPUShares     = 2
+)
+
+type ResourceLimitsRequest struct {
+	Memory        int64                 `json:"memory,omitempty"`
+	MemorySwap    int64                 `json:"memory_swap,omitempty"`
+	CPUShares     int64                 `json:"cpu_shares,omitempty"`
+	CPUPeriod     int64                 `json:"cpu_period,omitempty"`
+	CPUQuota      int64                 `json:"cpu_quota,omitempty"`
+	CpusetCpus    string                `json:"cpuset_cpus,omitempty"`
+	PidsLimit     *int64                `json:"pids_limit,omitempty"`
+	RestartPolicy *RestartPolicyRequest `json:"restart_policy,omitempty"`
+}
+
+type RestartPolicyRequest struct {
+	Name              string `json:"name"`
+	MaximumRetryCount int    `json:"maximum_retry_count,omitempty"`
+}
+
+// ContainerResources handles GET (current limits) and POST (update limits)
+// for a running container without recreating it.
+func (h *DockerHandler) ContainerResources(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	containerID := r.URL.Query().Get("id")
+	if containerID == "" {
+		writeError(w, http.StatusBadRequest, "Container ID is required")
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		container, err := client.InspectContainer(r.Context(), containerID)
+		if err != nil {
+			log.Printf("Failed to inspect container %s: %v", containerID, err)
+			writeDockerError(w, err)
+			return
+		}
+		writeSuccess(w, container.HostConfig)
+		return
+	}
+
+	var req ResourceLimitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	info, err := client.GetInfo(r.Context())
+	if err != nil {
+		log.Printf("Failed to get Docker info: %v", err)
+		writeDockerError(w, err)
+		return
+	}
+
+	config, err := validateResourceLimits(req, info)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	warnings, err := client.UpdateContainer(r.Context(), containerID, config)
+	if err != nil {
+		log.Printf("Failed to update container %s: %v", containerID, err)
+		writeDockerError(w, err)
+		return
+	}
+
+	writeSuccess(w, map[string]interface{}{
+		"status":    "updated",
+		"container": containerID,
+		"warnings":  warnings,
+	})
+}
+
+func validateResourceLimits(req ResourceLimitsRequest, info *docker.Info) (docker.UpdateConfig, error) {
+	config := docker.UpdateConfig{
+		Memory:     req.Memory,
+		MemorySwap: req.MemorySwap,
+		CPUShares:  req.CPUShares,
+		CPUPeriod:  req.CPUPeriod,
+		CPUQuota:   req.CPUQuota,
+		CpusetCpus: req.CpusetCpus,
+		PidsLimit:  req.PidsLimit,
+	}
+
+	if req.Memory < 0 {
+		return config, fmt.Errorf("memory must not be negative")
+	}
+	if req.Memory > 0 && req.Memory < minMemoryLimit {
+		return config, fmt.Errorf("memory must be at least %d bytes", minMemoryLimit)
+	}
+	if info.MemTotal > 0 && req.Memory > info.MemTotal {
+		return config, fmt.Errorf("memory %d exceeds host memory %d", req.Memory, info.MemTotal)
+	}
+
+	// -1 means unlimited swap; otherwise it is memory plus swap
+	if req.MemorySwap < -1 {
+		return config, fmt.Errorf("memory_swap must be -1 or a positive value")
+	}
+	if req.MemorySwap > 0 {
+		if req.Memory == 0 {
+			return config, fmt.Errorf("memory_swap requires memory to be set")
+		}
+		if req.MemorySwap < req.Memory {
+			return config, fmt.Errorf("memory_swap must be greater than or equal to memory")
+		}
+	}
+
+	if req.CPUShares != 0 && req.CPUShares < minCPUShares {
+		return config, fmt.Errorf("cpu_shares must be at least %d", minCPUShares)
+	}
+
+	if req.CPUPeriod < 0 || req.CPUQuota < 0 {
+		return config, fmt.Errorf("cpu_period and cpu_quota must not be negative")
+	}
+	if req.CPUPeriod != 0 && (req.CPUPeriod < 1000 || req.CPUPeriod > 1000000) {
+		return config, fmt.Errorf("cpu_period must be between 1000 and 1000000")
+	}
+	if req.CPUQuota > 0 {
+		if req.CPUQuota < minCPUQuota {
+			return config, fmt.Errorf("cpu_quota must be at least %d", minCPUQuota)
+		}
+		period := req.CPUPeriod
+		if period == 0 {
+			period = defaultCPUPeriod
+		}
+		if info.NCPU > 0 && req.CPUQuota > period*int64(info.NCPU) {
+			return config, fmt.Errorf("cpu_quota allows %.2f CPUs but the host has %d",
+				float64(req.CPUQuota)/float64(period), info.NCPU)
+		}
+	}
+
+	if req.CpusetCpus != "" {
+		if err := validateCpuset(req.CpusetCpus, info.NCPU); err != nil {
+			return config, err
+		}
+	}
+
+	if req.PidsLimit != nil && *req.PidsLimit < -1 {
+		return config, fmt.Errorf("pids_limit must be -1 (unlimited) or a positive value")
+	}
+
+	if req.RestartPolicy != nil {
+		switch req.RestartPolicy.Name {
+		case "no", "always", "unless-stopped":
+			if req.RestartPolicy.MaximumRetryCount != 0 {
+				return config, fmt.Errorf("maximum_retry_count is only valid with the on-failure policy")
+			}
+		case "on-failure":
+			if req.RestartPolicy.MaximumRetryCount < 0 {
+				return config, fmt.Errorf("maximum_retry_count must not be negative")
+			}
+		default:
+			return config, fmt.Errorf("invalid restart policy: %s", req.RestartPolicy.Name)
+		}
+		config.RestartPolicy = &docker.RestartPolicy{
+			Name:              req.RestartPolicy.Name,
+			MaximumRetryCount: req.RestartPolicy.MaximumRetryCount,
+		}
+	}
+
+	return config, nil
+}
+
+// validateCpuset checks a cpuset list such as "0-2,4" against the host CPU count.
+func validateCpuset(cpuset string, ncpu int) error {
+	for _, part := range strings.Split(cpuset, ",") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		if !isRange {
+			hi = lo
+		}
+
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return fmt.Errorf("invalid cpuset_cpus: %s", cpuset)
+		}
+		end, err := strconv.Atoi(hi)
+		if err != nil || start < 0 || end < start {
+			return fmt.Errorf("invalid cpuset_cpus: %s", cpuset)
+		}
+		if ncpu > 0 && end >= ncpu {
+			return fmt.Errorf("cpuset_cpus references CPU %d but the host has %d", end, ncpu)
+		}
+	}
+	return nil
+}
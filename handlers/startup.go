@@ -0,0 +1,234 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handlers for container startup ordering endpoints
+
+package handlers
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/startup"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+type StartupHandler struct {
+	store     *database.StartupStore
+	sequencer *startup.Sequencer
+}
+
+type SetStartupEntryRequest struct {
+	ContainerName  string   `json:"container_name"`
+	Group          int      `json:"group"`
+	DependsOn      []string `json:"depends_on,omitempty"`
+	WaitHealthy    *bool    `json:"wait_healthy,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+	Enabled        *bool    `json:"enabled,omitempty"`
+	// DisableRestartPolicy switches a restart policy of always or
+	// unless-stopped to no instead of rejecting the entry.
+	DisableRestartPolicy bool `json:"disable_restart_policy,omitempty"`
+}
+
+// StartupEntryResponse is a saved entry with the changes made to the
+// container along the way.
+type StartupEntryResponse struct {
+	*database.StartupEntry
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func NewStartupHandler(store *database.StartupStore, sequencer *startup.Sequencer) *StartupHandler {
+	return &StartupHandler{
+		store:     store,
+		sequencer: sequencer,
+	}
+}
+
+func (h *StartupHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	entries, err := h.store.List()
+	if err != nil {
+		log.Printf("Failed to list startup entries: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, entries)
+}
+
+func (h *StartupHandler) SetEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req SetStartupEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.ContainerName == "" {
+		writeError(w, http.StatusBadRequest, "Container name is required")
+		return
+	}
+
+	if req.TimeoutSeconds < 0 {
+		writeError(w, http.StatusBadRequest, "Timeout must not be negative")
+		return
+	}
+
+	entry := &database.StartupEntry{
+		ContainerName:  req.ContainerName,
+		Group:          req.Group,
+		DependsOn:      req.DependsOn,
+		WaitHealthy:    true,
+		TimeoutSeconds: int(startup.DefaultTimeout.Seconds()),
+		Enabled:        true,
+	}
+	if req.WaitHealthy != nil {
+		entry.WaitHealthy = *req.WaitHealthy
+	}
+	if req.TimeoutSeconds > 0 {
+		entry.TimeoutSeconds = req.TimeoutSeconds
+	}
+	if req.Enabled != nil {
+		entry.Enabled = *req.Enabled
+	}
+
+	// Reject changes that would make the order unsatisfiable
+	entries, err := h.store.List()
+	if err != nil {
+		log.Printf("Failed to list startup entries: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	candidate := []database.StartupEntry{*entry}
+	for _, e := range entries {
+		if e.ContainerName != entry.ContainerName {
+			candidate = append(candidate, e)
+		}
+	}
+	if _, err := startup.BuildPlan(candidate); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Docker starts containers with an automatic restart policy itself,
+	// before the order could apply
+	var warnings []string
+	if entry.Enabled {
+		warning, err := h.sequencer.CheckRestartPolicy(r.Context(), entry.ContainerName, req.DisableRestartPolicy)
+		if errors.Is(err, startup.ErrAutoRestart) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to check restart policy of %s: %v", req.ContainerName, err)
+			writeDockerError(w, err)
+			return
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	if err := h.store.Set(entry); err != nil {
+		log.Printf("Failed to set startup entry for %s: %v", req.ContainerName, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	saved, err := h.store.Get(req.ContainerName)
+	if err != nil {
+		log.Printf("Failed to get startup entry for %s: %v", req.ContainerName, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, StartupEntryResponse{StartupEntry: saved, Warnings: warnings})
+}
+
+func (h *StartupHandler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	name := r.URL.Query().Get("container")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "Container name is required")
+		return
+	}
+
+	if err := h.store.Delete(name); err != nil {
+		log.Printf("Failed to delete startup entry for %s: %v", name, err)
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeSuccess(w, map[string]string{
+		"status":    "deleted",
+		"container": name,
+	})
+}
+
+func (h *StartupHandler) Plan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	stages, err := h.sequencer.Plan()
+	if err != nil {
+		log.Printf("Failed to build startup plan: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeSuccess(w, stages)
+}
+
+func (h *StartupHandler) StartAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	results, err := h.sequencer.StartAll(r.Context())
+	if err != nil {
+		log.Printf("Failed to run startup sequence: %v", err)
+		writeDockerError(w, err)
+		return
+	}
+
+	writeSuccess(w, results)
+}
+
+func (h *StartupHandler) StopAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	results, err := h.sequencer.StopAll(r.Context())
+	if err != nil {
+		log.Printf("Failed to run shutdown sequence: %v", err)
+		writeDockerError(w, err)
+		return
+	}
+
+	writeSuccess(w, results)
+}
+
+func (h *StartupHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/docker/startup", h.ListEntries)
+	mux.HandleFunc("/docker/startup/set", h.SetEntry)
+	mux.HandleFunc("/docker/startup/delete", h.DeleteEntry)
+	mux.HandleFunc("/docker/startup/plan", h.Plan)
+	mux.HandleFunc("/docker/startup/start", h.StartAll)
+	mux.HandleFunc("/docker/startup/stop", h.StopAll)
+}
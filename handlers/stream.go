@@ -0,0 +1,55 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Newline-delimited JSON streaming for long-running operations
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// streamWriter writes one JSON object per line and flushes after each, so
+// clients can follow the progress of builds, pulls and similar operations.
+// The last line of a stream is always an APIResponse. It is safe for
+// concurrent use.
+type streamWriter struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	encoder *json.Encoder
+}
+
+func newStreamWriter(w http.ResponseWriter) *streamWriter {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	return &streamWriter{
+		w:       w,
+		flusher: flusher,
+		encoder: json.NewEncoder(w),
+	}
+}
+
+func (s *streamWriter) send(v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.encoder.Encode(v)
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
+
+func (s *streamWriter) success(data interface{}) {
+	s.send(APIResponse{Success: true, Data: data})
+}
+
+func (s *streamWriter) fail(message string, data interface{}) {
+	s.send(APIResponse{Success: false, Error: message, Data: data})
+}
+
+// wantsStream reports whether the client asked for a streamed response.
+func wantsStream(r *http.Request) bool {
+	return r.URL.Query().Get("stream") == "true"
+}
@@ -0,0 +1,249 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: HTTP handlers for offline image export and import
+
+package handlers
+
+import (
+	"bluenode-helper/docker"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// progressInterval limits how often byte-count progress is streamed.
+const progressInterval = time.Second
+
+type ExportImagesRequest struct {
+	Images    []string `json:"images"`
+	Path      string   `json:"path"`
+	Overwrite bool     `json:"overwrite,omitempty"`
+}
+
+type ImportImagesRequest struct {
+	Path     string `json:"path"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+type ImageTransferResult struct {
+	Path     string   `json:"path"`
+	Size     int64    `json:"size"`
+	Checksum string   `json:"checksum"`
+	Images   []string `json:"images"`
+}
+
+type TransferProgress struct {
+	Status string `json:"status"`
+	Bytes  int64  `json:"bytes"`
+	Total  int64  `json:"total,omitempty"`
+}
+
+// ExportImages saves one or more images to a tarball on the NAS and writes a
+// sha256sum-compatible checksum file next to it.
+func (h *DockerHandler) ExportImages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	var req ExportImagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Images) == 0 {
+		writeError(w, http.StatusBadRequest, "At least one image is required")
+		return
+	}
+	if !filepath.IsAbs(req.Path) {
+		writeError(w, http.StatusBadRequest, "An absolute tarball path is required")
+		return
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if !req.Overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(req.Path, flags, 0640)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var stream *streamWriter
+	if wantsStream(r) {
+		stream = newStreamWriter(w)
+	}
+
+	hash := sha256.New()
+	progress := newProgressWriter("exporting", 0, stream)
+	size, err := client.SaveImages(r.Context(), req.Images, io.MultiWriter(f, hash, progress))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(req.Path)
+		log.Printf("Failed to export images %v: %v", req.Images, err)
+		failTransfer(w, stream, err)
+		return
+	}
+
+	result := ImageTransferResult{
+		Path:     req.Path,
+		Size:     size,
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+		Images:   req.Images,
+	}
+
+	checksumLine := fmt.Sprintf("%s  %s\n", result.Checksum, filepath.Base(req.Path))
+	if err := os.WriteFile(req.Path+".sha256", []byte(checksumLine), 0640); err != nil {
+		log.Printf("Failed to write checksum file for %s: %v", req.Path, err)
+	}
+
+	if stream != nil {
+		stream.success(result)
+		return
+	}
+	writeSuccess(w, result)
+}
+
+// ImportImages verifies a tarball's checksum and loads it into Docker. The
+// expected checksum comes from the request or from a .sha256 file next to
+// the tarball.
+func (h *DockerHandler) ImportImages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	client, ok := h.clientFor(w, r)
+	if !ok {
+		return
+	}
+
+	var req ImportImagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !filepath.IsAbs(req.Path) {
+		writeError(w, http.StatusBadRequest, "An absolute tarball path is required")
+		return
+	}
+
+	expected := strings.ToLower(strings.TrimSpace(req.Checksum))
+	if expected == "" {
+		if data, err := os.ReadFile(req.Path + ".sha256"); err == nil {
+			expected = strings.ToLower(strings.Fields(string(data) + " ")[0])
+		}
+	}
+
+	f, err := os.Open(req.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var stream *streamWriter
+	if wantsStream(r) {
+		stream = newStreamWriter(w)
+	}
+
+	// Verify before loading so a corrupt tarball never reaches Docker
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(hash, newProgressWriter("verifying", info.Size(), stream)), f); err != nil {
+		failTransfer(w, stream, fmt.Errorf("failed to read tarball: %w", err))
+		return
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if expected != "" && checksum != expected {
+		msg := fmt.Sprintf("checksum mismatch: expected %s, got %s", expected, checksum)
+		if stream != nil {
+			stream.fail(msg, nil)
+			return
+		}
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		failTransfer(w, stream, err)
+		return
+	}
+
+	var onMessage func(docker.JSONMessage)
+	if stream != nil {
+		onMessage = func(msg docker.JSONMessage) { stream.send(msg) }
+	}
+	progress := newProgressWriter("loading", info.Size(), stream)
+	loaded, err := client.LoadImages(r.Context(), io.TeeReader(f, progress), onMessage)
+	if err != nil {
+		log.Printf("Failed to import images from %s: %v", req.Path, err)
+		failTransfer(w, stream, err)
+		return
+	}
+
+	result := ImageTransferResult{
+		Path:     req.Path,
+		Size:     info.Size(),
+		Checksum: checksum,
+		Images:   loaded,
+	}
+
+	if stream != nil {
+		stream.success(result)
+		return
+	}
+	writeSuccess(w, result)
+}
+
+func failTransfer(w http.ResponseWriter, stream *streamWriter, err error) {
+	if stream != nil {
+		stream.fail(err.Error(), nil)
+		return
+	}
+	writeDockerError(w, err)
+}
+
+// progressWriter counts bytes and periodically streams a TransferProgress.
+type progressWriter struct {
+	status   string
+	total    int64
+	written  int64
+	stream   *streamWriter
+	lastSent time.Time
+}
+
+func newProgressWriter(status string, total int64, stream *streamWriter) *progressWriter {
+	return &progressWriter{status: status, total: total, stream: stream}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if p.stream != nil && time.Since(p.lastSent) >= progressInterval {
+		p.lastSent = time.Now()
+		p.stream.send(TransferProgress{Status: p.status, Bytes: p.written, Total: p.total})
+	}
+	return len(b), nil
+}
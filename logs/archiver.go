@@ -0,0 +1,247 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Continuous archival of container logs
+
+package logs
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/docker"
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxBytes = 512 * 1024 * 1024
+	// reconcileInterval is how quickly newly started or added containers are
+	// picked up.
+	reconcileInterval = 10 * time.Second
+	pruneInterval     = 5 * time.Minute
+	flushInterval     = time.Second
+	flushLines        = 500
+)
+
+// Archiver follows the logs of every running container that is an enabled
+// target and stores each line in the log archive.
+type Archiver struct {
+	client   *docker.Client
+	store    *database.LogStore
+	maxBytes int64
+
+	wake chan struct{}
+
+	mu        sync.Mutex
+	followers map[string]*follower
+}
+
+type follower struct {
+	containerID string
+	cancel      context.CancelFunc
+}
+
+func NewArchiver(client *docker.Client, store *database.LogStore, maxBytes int64) *Archiver {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+
+	return &Archiver{
+		client:    client,
+		store:     store,
+		maxBytes:  maxBytes,
+		wake:      make(chan struct{}, 1),
+		followers: make(map[string]*follower),
+	}
+}
+
+func (a *Archiver) MaxBytes() int64 {
+	return a.maxBytes
+}
+
+// Following returns the names of containers whose logs are being followed.
+func (a *Archiver) Following() map[string]bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	following := make(map[string]bool, len(a.followers))
+	for name := range a.followers {
+		following[name] = true
+	}
+	return following
+}
+
+// Run blocks until ctx is cancelled, keeping one follower per archived
+// container and enforcing the size limit.
+func (a *Archiver) Run(ctx context.Context) {
+	log.Printf("Log archiver started (limit %d MB)", a.maxBytes/(1024*1024))
+
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	a.reconcile(ctx)
+	lastPrune := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Log archiver stopped")
+			return
+		case <-a.wake:
+			a.reconcile(ctx)
+		case now := <-ticker.C:
+			a.reconcile(ctx)
+
+			if now.Sub(lastPrune) >= pruneInterval {
+				lastPrune = now
+				if removed, err := a.store.Prune(a.maxBytes); err != nil {
+					log.Printf("Log pruning failed: %v", err)
+				} else if removed > 0 {
+					log.Printf("Pruned %d archived log lines", removed)
+				}
+			}
+		}
+	}
+}
+
+// Wake makes Run apply target changes now instead of at the next tick.
+func (a *Archiver) Wake() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// reconcile starts followers for targets that are running and stops those
+// whose target was removed or disabled, or whose container was replaced.
+func (a *Archiver) reconcile(ctx context.Context) {
+	targets, err := a.store.ListTargets()
+	if err != nil {
+		log.Printf("Failed to list log targets: %v", err)
+		return
+	}
+
+	wanted := make(map[string]bool)
+	for _, target := range targets {
+		if target.Enabled {
+			wanted[target.ContainerName] = true
+		}
+	}
+
+	running := make(map[string]string)
+	if len(wanted) > 0 {
+		containers, err := a.client.ListContainers(ctx, false)
+		if err != nil {
+			log.Printf("Failed to list containers for log archival: %v", err)
+			return
+		}
+		for _, container := range containers {
+			if len(container.Names) == 0 {
+				continue
+			}
+			running[strings.TrimPrefix(container.Names[0], "/")] = container.ID
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for name, f := range a.followers {
+		if !wanted[name] || running[name] != f.containerID {
+			f.cancel()
+			delete(a.followers, name)
+		}
+	}
+
+	for name := range wanted {
+		id, ok := running[name]
+		if !ok {
+			continue
+		}
+		if _, ok := a.followers[name]; ok {
+			continue
+		}
+
+		followCtx, cancel := context.WithCancel(ctx)
+		f := &follower{containerID: id, cancel: cancel}
+		a.followers[name] = f
+		go a.follow(followCtx, f, name)
+	}
+}
+
+// follow archives one container until it stops or the follower is
+// cancelled. It resumes after the newest archived line of the container, so
+// a recreated container continues the same history.
+func (a *Archiver) follow(ctx context.Context, f *follower, name string) {
+	defer a.forget(name, f)
+
+	details, err := a.client.InspectContainer(ctx, f.containerID)
+	if err != nil {
+		log.Printf("Failed to inspect %s for log archival: %v", name, err)
+		return
+	}
+
+	since, err := a.store.LastTime(name)
+	if err != nil {
+		log.Printf("Failed to resume log archival for %s: %v", name, err)
+		return
+	}
+	if !since.IsZero() {
+		since = since.Add(time.Nanosecond)
+	}
+
+	lines := make(chan docker.LogLine, flushLines)
+	done := make(chan error, 1)
+	go func() {
+		done <- a.client.FollowLogs(ctx, f.containerID, since, details.Config.Tty, func(line docker.LogLine) {
+			lines <- line
+		})
+		close(lines)
+	}()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []database.LogEntry
+	flush := func() {
+		if err := a.store.Append(batch); err != nil {
+			log.Printf("Failed to archive logs of %s: %v", name, err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				flush()
+				if err := <-done; err != nil {
+					log.Printf("Log archival of %s interrupted: %v", name, err)
+				}
+				return
+			}
+			batch = append(batch, database.LogEntry{
+				ContainerName: name,
+				ContainerID:   f.containerID,
+				Stream:        line.Stream,
+				Time:          line.Time,
+				Line:          line.Text,
+			})
+			if len(batch) >= flushLines {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// forget removes a finished follower unless it was already replaced.
+func (a *Archiver) forget(name string, f *follower) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f.cancel()
+	if a.followers[name] == f {
+		delete(a.followers, name)
+	}
+}
@@ -4,9 +4,17 @@
 package main
 
 import (
+	"bluenode-helper/assistant"
+	"bluenode-helper/backup"
 	"bluenode-helper/database"
+	"bluenode-helper/docker"
 	"bluenode-helper/handlers"
+	"bluenode-helper/logs"
+	"bluenode-helper/metrics"
 	"bluenode-helper/ollama"
+	"bluenode-helper/proxy"
+	"bluenode-helper/startup"
+	"bluenode-helper/supervisor"
 	"context"
 	"flag"
 	"fmt"
@@ -15,6 +23,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
@@ -24,6 +33,8 @@ const (
 	socketPath = "/var/run/bnhelper.sock"
 	// Graceful shutdown timeout
 	shutdownTimeout = 30 * time.Second
+	// Time allowed for stopping ordered containers on shutdown
+	containerStopTimeout = 2 * time.Minute
 )
 
 func main() {
@@ -72,11 +83,31 @@ func main() {
 	}
 	defer aiDB.Close()
 
+	// Initialize container log archive database
+	logDB, err := database.NewLogDB("")
+	if err != nil {
+		log.Fatalf("Failed to initialize log database: %v", err)
+	}
+	defer logDB.Close()
+
+	// Context for background workers, cancelled on shutdown
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
 	// Create HTTP server
 	mux := http.NewServeMux()
 
+	// Initialize encryption for stored secrets
+	secretBox, err := database.NewSecretBox("")
+	if err != nil {
+		log.Fatalf("Failed to initialize secret storage: %v", err)
+	}
+
 	// Register Docker API handlers
-	dockerHandler := handlers.NewDockerHandler()
+	dockerClient := docker.NewLocalClient()
+	registryStore := database.NewRegistryStore(db, secretBox)
+	endpointStore := database.NewEndpointStore(db, secretBox)
+	dockerHandler := handlers.NewDockerHandler(dockerClient, registryStore, endpointStore)
 	dockerHandler.RegisterRoutes(mux)
 
 	// Register Configuration API handlers
@@ -84,6 +115,62 @@ func main() {
 	configHandler := handlers.NewConfigHandler(configStore)
 	configHandler.RegisterRoutes(mux)
 
+	// Register container health policy handlers and start the supervisor
+	healthStore := database.NewHealthStore(db)
+	healthHandler := handlers.NewHealthHandler(healthStore)
+	healthHandler.RegisterRoutes(mux)
+
+	supervisorInterval := supervisor.DefaultInterval
+	if config, err := configStore.Get("docker.health.interval"); err == nil {
+		if seconds, err := strconv.Atoi(config.Value); err == nil && seconds > 0 {
+			supervisorInterval = time.Duration(seconds) * time.Second
+		}
+	}
+	healthSupervisor := supervisor.New(dockerClient, healthStore, supervisorInterval)
+	go healthSupervisor.Run(bgCtx)
+
+	// Register metrics history handlers and start sampling
+	metricsStore := database.NewMetricsStore(db, nil)
+	metricsHandler := handlers.NewMetricsHandler(metricsStore)
+	metricsHandler.RegisterRoutes(mux)
+
+	metricsInterval := metrics.DefaultInterval
+	if config, err := configStore.Get("docker.metrics.interval"); err == nil {
+		if seconds, err := strconv.Atoi(config.Value); err == nil && seconds > 0 {
+			metricsInterval = time.Duration(seconds) * time.Second
+		}
+	}
+	if config, err := configStore.Get("docker.metrics.enabled"); err != nil || config.Value != "false" {
+		metricsCollector := metrics.NewCollector(dockerClient, metricsStore, metricsInterval)
+		go metricsCollector.Run(bgCtx)
+	}
+
+	// Register log archive handlers and start archiving
+	logStore := database.NewLogStore(logDB)
+	var logArchiver *logs.Archiver
+	if config, err := configStore.Get("docker.logs.enabled"); err != nil || config.Value != "false" {
+		var logMaxBytes int64
+		if config, err := configStore.Get("docker.logs.max_size_mb"); err == nil {
+			if megabytes, err := strconv.Atoi(config.Value); err == nil && megabytes > 0 {
+				logMaxBytes = int64(megabytes) * 1024 * 1024
+			}
+		}
+		logArchiver = logs.NewArchiver(dockerClient, logStore, logMaxBytes)
+		go logArchiver.Run(bgCtx)
+	}
+	logsHandler := handlers.NewLogsHandler(logStore, logArchiver)
+	logsHandler.RegisterRoutes(mux)
+
+	// Register startup ordering handlers and run the boot sequence
+	startupStore := database.NewStartupStore(db)
+	sequencer := startup.New(dockerClient, startupStore)
+	startupHandler := handlers.NewStartupHandler(startupStore, sequencer)
+	startupHandler.RegisterRoutes(mux)
+
+	if config, err := configStore.Get("docker.startup.on_boot"); err != nil || config.Value != "false" {
+		go sequencer.Boot(bgCtx)
+	}
+
 	// Initialize default configurations if not set
 	if _, err := configStore.Get("ollama.default_model"); err != nil {
 		configStore.Set("ollama.default_model", "qwen2.5:0.5b", "Default Ollama model for chat")
@@ -92,11 +179,54 @@ func main() {
 		configStore.Set("ollama.system_prompt", "You are BlueNode Helper, an AI assistant for the BlueNode Server OS.", "System prompt for Ollama chat")
 	}
 
+	// Register volume backup handlers
+	backupDir := ""
+	if config, err := configStore.Get("docker.backup.dir"); err == nil {
+		backupDir = config.Value
+	}
+	backupStore := database.NewBackupStore(db)
+	backupManager := backup.NewManager(dockerClient, backupStore, backupDir)
+	backupHandler := handlers.NewBackupHandler(backupManager, backupStore)
+	backupHandler.RegisterRoutes(mux)
+
+	// Start the web proxy for container UIs. It adds no authentication of
+	// its own, so it only runs when turned on.
+	var proxyServer *http.Server
+	if config, err := configStore.Get("proxy.enabled"); err == nil && config.Value == "true" {
+		proxyListen := proxy.DefaultListen
+		if config, err := configStore.Get("proxy.listen"); err == nil && config.Value != "" {
+			proxyListen = config.Value
+		}
+		proxyDomain := ""
+		if config, err := configStore.Get("proxy.domain"); err == nil {
+			proxyDomain = config.Value
+		}
+
+		webProxy := proxy.New(dockerClient, proxyDomain)
+		proxyHandler := handlers.NewProxyHandler(webProxy)
+		proxyHandler.RegisterRoutes(mux)
+		go webProxy.Run(bgCtx)
+
+		proxyServer = &http.Server{
+			Addr:              proxyListen,
+			Handler:           webProxy,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			log.Printf("Web proxy listening on %s", proxyListen)
+			if err := proxyServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("Web proxy error: %v", err)
+			}
+		}()
+	}
+
 	// Register Ollama API handlers
 	ollamaClient := ollama.NewClient("")
 	chatStore := database.NewChatStore(aiDB)
 	fileIndexStore := database.NewFileIndexStore(aiDB)
-	ollamaHandler := handlers.NewOllamaHandler(ollamaClient, chatStore, fileIndexStore, configStore)
+	actionStore := database.NewActionStore(aiDB)
+	toolbox := assistant.NewToolbox(dockerClient)
+	ollamaHandler := handlers.NewOllamaHandler(bgCtx, ollamaClient, chatStore, fileIndexStore, configStore, actionStore, toolbox)
 	ollamaHandler.RegisterRoutes(mux)
 
 	// Health endpoint
@@ -136,10 +266,38 @@ func main() {
 	case sig := <-shutdown:
 		log.Printf("Received signal: %v. Starting graceful shutdown...", sig)
 
+		// Stop background workers
+		stopBackground()
+
+		// Stop ordered containers in reverse start order. Unless configured
+		// otherwise this only happens when the machine is shutting down, so
+		// restarting the helper leaves the containers running.
+		stopContainers := false
+		if config, err := configStore.Get("docker.startup.stop_on_shutdown"); err == nil && config.Value != "" {
+			stopContainers = config.Value == "true"
+		} else {
+			checkCtx, cancelCheck := context.WithTimeout(context.Background(), 5*time.Second)
+			stopContainers = startup.SystemStopping(checkCtx)
+			cancelCheck()
+		}
+		if stopContainers {
+			stopCtx, cancelStop := context.WithTimeout(context.Background(), containerStopTimeout)
+			if _, err := sequencer.StopAll(stopCtx); err != nil {
+				log.Printf("Shutdown sequence failed: %v", err)
+			}
+			cancelStop()
+		}
+
 		// Create context with timeout for shutdown
 		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 
+		if proxyServer != nil {
+			if err := proxyServer.Shutdown(ctx); err != nil {
+				log.Printf("Web proxy shutdown failed: %v", err)
+			}
+		}
+
 		// Attempt graceful shutdown
 		if err := server.Shutdown(ctx); err != nil {
 			log.Printf("Graceful shutdown failed, forcing shutdown: %v", err)
@@ -0,0 +1,180 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Periodic container metrics sampling
+
+package metrics
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/docker"
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval = time.Minute
+	// maxConcurrent bounds parallel stats requests, each of which takes the
+	// daemon about a second to answer.
+	maxConcurrent = 8
+	pruneInterval = time.Hour
+)
+
+// Collector samples every running container once per interval and stores the
+// results in the metrics history.
+type Collector struct {
+	client   *docker.Client
+	store    *database.MetricsStore
+	interval time.Duration
+
+	mu       sync.Mutex
+	counters map[string]counters
+}
+
+// counters are the cumulative I/O totals from the previous sample, used to
+// turn the daemon's running totals into per-sample deltas.
+type counters struct {
+	containerID string
+	netRx       uint64
+	netTx       uint64
+	blockRead   uint64
+	blockWrite  uint64
+}
+
+func NewCollector(client *docker.Client, store *database.MetricsStore, interval time.Duration) *Collector {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Collector{
+		client:   client,
+		store:    store,
+		interval: interval,
+		counters: make(map[string]counters),
+	}
+}
+
+// Run blocks until ctx is cancelled, sampling once per interval and pruning
+// expired history once an hour.
+func (c *Collector) Run(ctx context.Context) {
+	log.Printf("Metrics collector started (interval %s)", c.interval)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	lastPrune := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Metrics collector stopped")
+			return
+		case now := <-ticker.C:
+			if err := c.CollectOnce(ctx); err != nil {
+				log.Printf("Metrics collection failed: %v", err)
+			}
+
+			if now.Sub(lastPrune) >= pruneInterval {
+				lastPrune = now
+				if removed, err := c.store.Prune(now); err != nil {
+					log.Printf("Metrics pruning failed: %v", err)
+				} else if removed > 0 {
+					log.Printf("Pruned %d expired metrics buckets", removed)
+				}
+			}
+		}
+	}
+}
+
+// CollectOnce samples all running containers.
+func (c *Collector) CollectOnce(ctx context.Context) error {
+	containers, err := c.client.ListContainers(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	sem := make(chan struct{}, maxConcurrent)
+	var wg sync.WaitGroup
+	for _, container := range containers {
+		if len(container.Names) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(container.Names[0], "/")
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id, name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			c.sample(ctx, now, id, name)
+		}(container.ID, name)
+	}
+	wg.Wait()
+
+	c.forgetStopped(containers)
+	return nil
+}
+
+func (c *Collector) sample(ctx context.Context, now time.Time, id, name string) {
+	stats, err := c.client.ContainerStats(ctx, id)
+	if err != nil {
+		log.Printf("Failed to get stats for %s: %v", name, err)
+		return
+	}
+
+	rx, tx := stats.NetworkBytes()
+	read, write := stats.BlockIO()
+	current := counters{containerID: id, netRx: rx, netTx: tx, blockRead: read, blockWrite: write}
+
+	c.mu.Lock()
+	previous, seen := c.counters[name]
+	c.counters[name] = current
+	c.mu.Unlock()
+
+	sample := database.MetricsSample{
+		ContainerName: name,
+		Time:          now,
+		CPUPercent:    stats.CPUPercent(),
+		MemoryBytes:   int64(stats.MemoryUsage()),
+		MemoryLimit:   int64(stats.MemoryStats.Limit),
+	}
+
+	// The first sample of a container only establishes the baseline
+	if seen && previous.containerID == id {
+		sample.NetRxBytes = delta(previous.netRx, rx)
+		sample.NetTxBytes = delta(previous.netTx, tx)
+		sample.BlockReadBytes = delta(previous.blockRead, read)
+		sample.BlockWriteBytes = delta(previous.blockWrite, write)
+	}
+
+	if err := c.store.Record(sample); err != nil {
+		log.Printf("Failed to record metrics for %s: %v", name, err)
+	}
+}
+
+// forgetStopped drops counters of containers that are no longer running, so
+// a restart starts from a fresh baseline.
+func (c *Collector) forgetStopped(running []docker.Container) {
+	ids := make(map[string]bool)
+	for _, container := range running {
+		ids[container.ID] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, counter := range c.counters {
+		if !ids[counter.containerID] {
+			delete(c.counters, name)
+		}
+	}
+}
+
+// delta handles counters that went backwards, which happens when a
+// container's network namespace is recreated.
+func delta(previous, current uint64) int64 {
+	if current < previous {
+		return int64(current)
+	}
+	return int64(current - previous)
+}
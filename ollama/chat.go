@@ -0,0 +1,64 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Chat requests with tool calling
+
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Tool describes a function the model may call. Parameters is a JSON schema
+// of the arguments object.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// SendChat sends a complete chat request and waits for the full response.
+func (c *Client) SendChat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	req.Stream = false
+
+	resp, err := c.send(ctx, c.httpClient, http.MethodPost, "/api/chat", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("chat", resp)
+	}
+
+	var chatResp ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &chatResp, nil
+}
+
+// IsToolsUnsupported reports whether err is Ollama refusing a request with
+// tools because the model was not trained for tool calling.
+func IsToolsUnsupported(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest && strings.Contains(apiErr.Message, "does not support tools")
+}
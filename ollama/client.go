@@ -23,14 +23,20 @@ httpClient *http.Client
 }
 
 type ChatRequest struct {
-Model    string    `json:"model"`
-Messages []Message `json:"messages"`
-Stream   bool      `json:"stream"`
+Model     string                 `json:"model"`
+Messages  []Message              `json:"messages"`
+Stream    bool                   `json:"stream"`
+Tools     []Tool                 `json:"tools,omitempty"`
+Options   map[string]interface{} `json:"options,omitempty"`
+KeepAlive string                 `json:"keep_alive,omitempty"`
 }
 
 type Message struct {
-Role    string `json:"role"`
-Content string `json:"content"`
+Role      string     `json:"role"`
+Content   string     `json:"content"`
+ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+ToolName  string     `json:"tool_name,omitempty"`
+Images    []string   `json:"images,omitempty"`
 }
 
 type ChatResponse struct {
@@ -54,9 +60,11 @@ Models []Model `json:"models"`
 }
 
 type Model struct {
-Name       string    `json:"name"`
-ModifiedAt time.Time `json:"modified_at"`
-Size       int64     `json:"size"`
+Name       string       `json:"name"`
+ModifiedAt time.Time    `json:"modified_at"`
+Size       int64        `json:"size"`
+Digest     string       `json:"digest"`
+Details    ModelDetails `json:"details"`
 }
 
 func NewClient(baseURL string) *Client {
@@ -73,41 +81,10 @@ Timeout: 5 * time.Minute,
 }
 
 func (c *Client) Chat(ctx context.Context, model string, messages []Message) (*ChatResponse, error) {
-req := ChatRequest{
+return c.SendChat(ctx, ChatRequest{
 Model:    model,
 Messages: messages,
-Stream:   false,
-}
-
-body, err := json.Marshal(req)
-if err != nil {
-return nil, fmt.Errorf("failed to marshal request: %w", err)
-}
-
-httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/api/chat", bytes.NewReader(body))
-if err != nil {
-return nil, fmt.Errorf("failed to create request: %w", err)
-}
-
-httpReq.Header.Set("Content-Type", "application/json")
-
-resp, err := c.httpClient.Do(httpReq)
-if err != nil {
-return nil, fmt.Errorf("failed to send request: %w", err)
-}
-defer resp.Body.Close()
-
-if resp.StatusCode != http.StatusOK {
-bodyBytes, _ := io.ReadAll(resp.Body)
-return nil, fmt.Errorf("ollama request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
-}
-
-var chatResp ChatResponse
-if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-return nil, fmt.Errorf("failed to decode response: %w", err)
-}
-
-return &chatResp, nil
+})
 }
 
 func (c *Client) GenerateEmbedding(ctx context.Context, model, text string) ([]float64, error) {
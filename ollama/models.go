@@ -0,0 +1,258 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Ollama model management: pull, delete, copy, show and running models
+
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned for a non-successful Ollama response, with the
+// message Ollama gave.
+type APIError struct {
+	Op         string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("ollama %s failed with status %d: %s", e.Op, e.StatusCode, e.Message)
+}
+
+type ModelDetails struct {
+	ParentModel       string   `json:"parent_model"`
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// PullProgress is one status update of a pull. Total and Completed are set
+// while a layer is downloading.
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ModelInfo struct {
+	Name       string                 `json:"name"`
+	License    string                 `json:"license,omitempty"`
+	Modelfile  string                 `json:"modelfile"`
+	Parameters string                 `json:"parameters"`
+	Template   string                 `json:"template"`
+	System     string                 `json:"system,omitempty"`
+	Details    ModelDetails           `json:"details"`
+	ModelInfo  map[string]interface{} `json:"model_info,omitempty"`
+	// Capabilities lists features such as completion, tools, vision and
+	// insert. Older Ollama versions leave it empty.
+	Capabilities []string  `json:"capabilities,omitempty"`
+	ModifiedAt   time.Time `json:"modified_at"`
+	// ParameterValues holds Parameters split into name and values, as a
+	// parameter such as stop can be given several times.
+	ParameterValues map[string][]string `json:"parameter_values"`
+}
+
+type RunningModel struct {
+	Name          string       `json:"name"`
+	Model         string       `json:"model"`
+	Size          int64        `json:"size"`
+	SizeVRAM      int64        `json:"size_vram"`
+	Digest        string       `json:"digest"`
+	Details       ModelDetails `json:"details"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+	ContextLength int          `json:"context_length,omitempty"`
+}
+
+type modelRequest struct {
+	Model  string `json:"model"`
+	Stream *bool  `json:"stream,omitempty"`
+}
+
+type copyRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+type runningModelsResponse struct {
+	Models []RunningModel `json:"models"`
+}
+
+// PullModel downloads a model from the registry and passes each progress
+// update to fn. Pulls can take far longer than the client's request timeout,
+// so they are made without one; cancel ctx to abort.
+func (c *Client) PullModel(ctx context.Context, name string, fn func(PullProgress)) error {
+	stream := true
+	resp, err := c.send(ctx, c.streamingClient(), http.MethodPost, "/api/pull", modelRequest{Model: name, Stream: &stream})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("pull", resp)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var progress PullProgress
+		if err := decoder.Decode(&progress); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode pull progress: %w", err)
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("ollama pull failed: %s", progress.Error)
+		}
+		if fn != nil {
+			fn(progress)
+		}
+	}
+}
+
+func (c *Client) DeleteModel(ctx context.Context, name string) error {
+	resp, err := c.send(ctx, c.httpClient, http.MethodDelete, "/api/delete", modelRequest{Model: name})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("delete", resp)
+	}
+	return nil
+}
+
+// CopyModel stores source under a second name, e.g. to give a model a
+// shorter tag. Layers are shared, so no disk space is used.
+func (c *Client) CopyModel(ctx context.Context, source, destination string) error {
+	resp, err := c.send(ctx, c.httpClient, http.MethodPost, "/api/copy", copyRequest{Source: source, Destination: destination})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError("copy", resp)
+	}
+	return nil
+}
+
+func (c *Client) ShowModel(ctx context.Context, name string) (*ModelInfo, error) {
+	resp, err := c.send(ctx, c.httpClient, http.MethodPost, "/api/show", modelRequest{Model: name})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("show", resp)
+	}
+
+	var info ModelInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	info.Name = name
+	info.ParameterValues = parseParameters(info.Parameters)
+
+	return &info, nil
+}
+
+// RunningModels lists the models currently loaded into memory.
+func (c *Client) RunningModels(ctx context.Context) ([]RunningModel, error) {
+	resp, err := c.send(ctx, c.httpClient, http.MethodGet, "/api/ps", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("list running models", resp)
+	}
+
+	var psResp runningModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&psResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if psResp.Models == nil {
+		psResp.Models = []RunningModel{}
+	}
+
+	return psResp.Models, nil
+}
+
+func (c *Client) send(ctx context.Context, httpClient *http.Client, method, path string, payload interface{}) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
+
+// streamingClient shares the regular client's transport but has no overall
+// timeout, for responses that stream for as long as an operation runs.
+func (c *Client) streamingClient() *http.Client {
+	return &http.Client{Transport: c.httpClient.Transport}
+}
+
+func newAPIError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+
+	var payload struct {
+		Error string `json:"error"`
+	}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		message = payload.Error
+	}
+
+	return &APIError{Op: op, StatusCode: resp.StatusCode, Message: message}
+}
+
+// parseParameters splits the parameters block of a Modelfile, one
+// "name value" pair per line, with quoted values unquoted.
+func parseParameters(block string) map[string][]string {
+	params := make(map[string][]string)
+	for _, line := range strings.Split(block, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		params[name] = append(params[name], value)
+	}
+	return params
+}
@@ -0,0 +1,475 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: JSON schema validation of structured model output
+
+package ollama
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSchemaErrors bounds how many violations are reported for one document.
+const maxSchemaErrors = 20
+
+// unsupportedKeywords would change what a document may contain, so a schema
+// using them is rejected rather than validated loosely.
+var unsupportedKeywords = map[string]bool{
+	"$ref":                  true,
+	"$dynamicRef":           true,
+	"if":                    true,
+	"then":                  true,
+	"else":                  true,
+	"dependentRequired":     true,
+	"dependentSchemas":      true,
+	"patternProperties":     true,
+	"propertyNames":         true,
+	"unevaluatedProperties": true,
+	"unevaluatedItems":      true,
+	"contains":              true,
+}
+
+// Schema checks model output against the subset of JSON schema that models
+// can be constrained to: types, enum and const, object properties, arrays,
+// string length and pattern, numeric bounds, and the allOf, anyOf, oneOf and
+// not combinators. Annotations such as description and format are ignored.
+type Schema struct {
+	root     map[string]interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+func ParseSchema(raw json.RawMessage) (*Schema, error) {
+	var root interface{}
+	if err := decodeJSON(raw, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	obj, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid schema: must be a JSON object")
+	}
+
+	s := &Schema{root: obj, patterns: make(map[string]*regexp.Regexp)}
+	if err := s.check(obj, "#"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate parses data as JSON and returns every violation of the schema,
+// each prefixed with the path of the offending value.
+func (s *Schema) Validate(data []byte) (interface{}, []string) {
+	var value interface{}
+	if err := decodeJSON(data, &value); err != nil {
+		return nil, []string{fmt.Sprintf("response is not valid JSON: %v", err)}
+	}
+
+	v := &validator{schema: s}
+	v.validate(s.root, value, "$")
+	return value, v.errors
+}
+
+// check walks the schema once, rejecting unsupported keywords and compiling
+// patterns.
+func (s *Schema) check(node map[string]interface{}, at string) error {
+	for key, value := range node {
+		if unsupportedKeywords[key] {
+			return fmt.Errorf("unsupported schema keyword %s at %s", key, at)
+		}
+
+		switch key {
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("invalid schema: pattern at %s must be a string", at)
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid schema: pattern at %s: %w", at, err)
+			}
+			s.patterns[pattern] = re
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("invalid schema: properties at %s must be an object", at)
+			}
+			for name, prop := range props {
+				if err := s.checkSubschema(prop, at+"/properties/"+name); err != nil {
+					return err
+				}
+			}
+		case "items", "additionalProperties", "not":
+			if _, ok := value.(bool); ok {
+				continue
+			}
+			if err := s.checkSubschema(value, at+"/"+key); err != nil {
+				return err
+			}
+		case "prefixItems", "allOf", "anyOf", "oneOf":
+			list, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("invalid schema: %s at %s must be an array", key, at)
+			}
+			for i, sub := range list {
+				if err := s.checkSubschema(sub, fmt.Sprintf("%s/%s/%d", at, key, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) checkSubschema(value interface{}, at string) error {
+	node, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid schema: %s must be an object", at)
+	}
+	return s.check(node, at)
+}
+
+type validator struct {
+	schema *Schema
+	errors []string
+}
+
+func (v *validator) fail(path, format string, args ...interface{}) {
+	if len(v.errors) < maxSchemaErrors {
+		v.errors = append(v.errors, path+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+// valid reports whether value matches node without recording errors, for
+// the combinators.
+func (v *validator) valid(node map[string]interface{}, value interface{}) bool {
+	sub := &validator{schema: v.schema}
+	sub.validate(node, value, "$")
+	return len(sub.errors) == 0
+}
+
+func (v *validator) validate(node map[string]interface{}, value interface{}, path string) {
+	if types, ok := node["type"]; ok && !matchesType(types, value) {
+		v.fail(path, "expected %s, got %s", describeTypes(types), typeOf(value))
+		return
+	}
+
+	if enum, ok := node["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if jsonEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "must be one of %s", encode(enum))
+		}
+	}
+	if constant, ok := node["const"]; ok && !jsonEqual(constant, value) {
+		v.fail(path, "must be %s", encode(constant))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(node, val, path)
+	case []interface{}:
+		v.validateArray(node, val, path)
+	case string:
+		v.validateString(node, val, path)
+	case json.Number:
+		v.validateNumber(node, val, path)
+	}
+
+	if all, ok := node["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			v.validate(sub.(map[string]interface{}), value, path)
+		}
+	}
+	if options, ok := node["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range options {
+			if v.valid(sub.(map[string]interface{}), value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "does not match any of the allowed schemas")
+		}
+	}
+	if one, ok := node["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range one {
+			if v.valid(sub.(map[string]interface{}), value) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(path, "must match exactly one schema, matched %d", matches)
+		}
+	}
+	if not, ok := node["not"].(map[string]interface{}); ok && v.valid(not, value) {
+		v.fail(path, "must not match the excluded schema")
+	}
+}
+
+func (v *validator) validateObject(node map[string]interface{}, obj map[string]interface{}, path string) {
+	if required, ok := node["required"].([]interface{}); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, present := obj[key]; !present {
+					v.fail(path, "missing required property %q", key)
+				}
+			}
+		}
+	}
+
+	if n, ok := schemaInt(node, "minProperties"); ok && len(obj) < n {
+		v.fail(path, "must have at least %d properties", n)
+	}
+	if n, ok := schemaInt(node, "maxProperties"); ok && len(obj) > n {
+		v.fail(path, "must have at most %d properties", n)
+	}
+
+	props, _ := node["properties"].(map[string]interface{})
+
+	// Sorted so errors come out in a stable order
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "." + key
+		if prop, ok := props[key].(map[string]interface{}); ok {
+			v.validate(prop, obj[key], childPath)
+			continue
+		}
+		switch additional := node["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(path, "unexpected property %q", key)
+			}
+		case map[string]interface{}:
+			v.validate(additional, obj[key], childPath)
+		}
+	}
+}
+
+func (v *validator) validateArray(node map[string]interface{}, arr []interface{}, path string) {
+	if n, ok := schemaInt(node, "minItems"); ok && len(arr) < n {
+		v.fail(path, "must have at least %d items", n)
+	}
+	if n, ok := schemaInt(node, "maxItems"); ok && len(arr) > n {
+		v.fail(path, "must have at most %d items", n)
+	}
+
+	prefix, _ := node["prefixItems"].([]interface{})
+	for i, item := range arr {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		if i < len(prefix) {
+			v.validate(prefix[i].(map[string]interface{}), item, itemPath)
+			continue
+		}
+		switch items := node["items"].(type) {
+		case bool:
+			if !items {
+				v.fail(path, "must have at most %d items", len(prefix))
+				return
+			}
+		case map[string]interface{}:
+			v.validate(items, item, itemPath)
+		}
+	}
+
+	if unique, _ := node["uniqueItems"].(bool); unique {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					v.fail(path, "items %d and %d are equal", i, j)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) validateString(node map[string]interface{}, str string, path string) {
+	length := utf8.RuneCountInString(str)
+	if n, ok := schemaInt(node, "minLength"); ok && length < n {
+		v.fail(path, "must be at least %d characters", n)
+	}
+	if n, ok := schemaInt(node, "maxLength"); ok && length > n {
+		v.fail(path, "must be at most %d characters", n)
+	}
+	if pattern, ok := node["pattern"].(string); ok {
+		if re := v.schema.patterns[pattern]; re != nil && !re.MatchString(str) {
+			v.fail(path, "does not match pattern %s", pattern)
+		}
+	}
+}
+
+func (v *validator) validateNumber(node map[string]interface{}, num json.Number, path string) {
+	f, err := num.Float64()
+	if err != nil {
+		v.fail(path, "invalid number %s", num)
+		return
+	}
+
+	if limit, ok := schemaFloat(node, "minimum"); ok && f < limit {
+		v.fail(path, "must be >= %v", limit)
+	}
+	if limit, ok := schemaFloat(node, "maximum"); ok && f > limit {
+		v.fail(path, "must be <= %v", limit)
+	}
+	if limit, ok := schemaFloat(node, "exclusiveMinimum"); ok && f <= limit {
+		v.fail(path, "must be > %v", limit)
+	}
+	if limit, ok := schemaFloat(node, "exclusiveMaximum"); ok && f >= limit {
+		v.fail(path, "must be < %v", limit)
+	}
+	if step, ok := schemaFloat(node, "multipleOf"); ok && step > 0 {
+		if q := f / step; math.Abs(q-math.Round(q)) > 1e-9 {
+			v.fail(path, "must be a multiple of %v", step)
+		}
+	}
+}
+
+func matchesType(types interface{}, value interface{}) bool {
+	switch t := types.(type) {
+	case string:
+		return matchesSingleType(t, value)
+	case []interface{}:
+		for _, name := range t {
+			if s, ok := name.(string); ok && matchesSingleType(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleType(name string, value interface{}) bool {
+	actual := typeOf(value)
+	switch name {
+	case "number":
+		return actual == "number" || actual == "integer"
+	default:
+		return actual == name
+	}
+}
+
+func describeTypes(types interface{}) string {
+	if list, ok := types.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(types)
+}
+
+// typeOf names a decoded value's JSON type. Numbers without a fractional
+// part count as integers, as JSON schema defines them mathematically.
+func typeOf(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case json.Number:
+		if f, err := val.Float64(); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
+
+func schemaInt(node map[string]interface{}, key string) (int, bool) {
+	f, ok := schemaFloat(node, key)
+	return int(f), ok
+}
+
+func schemaFloat(node map[string]interface{}, key string) (float64, bool) {
+	num, ok := node[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := num.Float64()
+	return f, err == nil
+}
+
+// jsonEqual compares decoded values, treating numbers by value so that 1 and
+// 1.0 are equal.
+func jsonEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		return aerr == nil && berr == nil && af == bf
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, value := range av {
+			other, ok := bv[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func encode(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// decodeJSON keeps numbers as json.Number so integers and large values are
+// checked exactly, and rejects trailing data after the document.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,388 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Label-driven reverse proxy for container web interfaces
+
+package proxy
+
+import (
+	"bluenode-helper/docker"
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Container labels read by the proxy.
+const (
+	LabelPort = "bluenode.web.port"
+	LabelPath = "bluenode.web.path"
+	LabelName = "bluenode.web.name"
+	LabelHost = "bluenode.web.host"
+	// LabelDirect lets the proxy reach a port that is not published on the
+	// host through the container's own IP address.
+	LabelDirect = "bluenode.web.direct"
+)
+
+const (
+	DefaultListen = "127.0.0.1:8088"
+	// AppsPrefix is the path under which apps are routed by name.
+	AppsPrefix = "/apps/"
+
+	retryInterval = 5 * time.Second
+)
+
+// Route is one container web interface reachable through the proxy.
+type Route struct {
+	Name        string   `json:"name"`
+	Container   string   `json:"container"`
+	ContainerID string   `json:"container_id"`
+	Target      string   `json:"target"`
+	Path        string   `json:"path"`
+	Prefix      string   `json:"prefix"`
+	Hosts       []string `json:"hosts,omitempty"`
+
+	handler http.Handler
+}
+
+// Proxy routes /apps/<name>/ and host names to containers that carry a
+// bluenode.web.port label. Routes are rebuilt from the container list on
+// every container start, stop or removal event.
+type Proxy struct {
+	client *docker.Client
+	domain string
+
+	mu     sync.RWMutex
+	byName map[string]*Route
+	byHost map[string]*Route
+}
+
+// New creates a proxy. When domain is set, every app is also reachable as
+// <name>.<domain>.
+func New(client *docker.Client, domain string) *Proxy {
+	return &Proxy{
+		client: client,
+		domain: strings.Trim(strings.ToLower(domain), "."),
+		byName: make(map[string]*Route),
+		byHost: make(map[string]*Route),
+	}
+}
+
+// Run keeps the routes up to date until ctx is cancelled, reconnecting to the
+// event stream whenever it drops.
+func (p *Proxy) Run(ctx context.Context) {
+	log.Println("Web proxy route watcher started")
+
+	filters := docker.Filters{}
+	filters.Add("type", "container")
+	filters.Add("event", "start", "die", "destroy", "rename", "update")
+
+	for {
+		if err := p.Refresh(ctx); err != nil {
+			log.Printf("Failed to refresh web proxy routes: %v", err)
+		}
+
+		err := p.client.Events(ctx, filters, func(docker.Event) {
+			if err := p.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh web proxy routes: %v", err)
+			}
+		})
+		if ctx.Err() != nil {
+			log.Println("Web proxy route watcher stopped")
+			return
+		}
+		if err != nil {
+			log.Printf("Web proxy lost the Docker event stream: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Web proxy route watcher stopped")
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// Refresh rebuilds the routing table from the running containers.
+func (p *Proxy) Refresh(ctx context.Context) error {
+	filters := docker.Filters{}
+	filters.Add("label", LabelPort)
+
+	containers, err := p.client.ListContainersWithOptions(ctx, docker.ContainerListOptions{Filters: filters})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		return containerName(containers[i]) < containerName(containers[j])
+	})
+
+	byName := make(map[string]*Route)
+	byHost := make(map[string]*Route)
+	for _, c := range containers {
+		route, err := p.buildRoute(c)
+		if err != nil {
+			log.Printf("Web proxy skipping %s: %v", containerName(c), err)
+			continue
+		}
+
+		if existing, ok := byName[route.Name]; ok {
+			log.Printf("Web proxy skipping %s: app name %s is already used by %s", route.Container, route.Name, existing.Container)
+			continue
+		}
+		byName[route.Name] = route
+
+		for _, host := range route.Hosts {
+			if _, ok := byHost[host]; !ok {
+				byHost[host] = route
+			}
+		}
+	}
+
+	p.mu.Lock()
+	p.byName = byName
+	p.byHost = byHost
+	p.mu.Unlock()
+
+	return nil
+}
+
+// Routes returns the current routes sorted by name.
+func (p *Proxy) Routes() []Route {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	routes := make([]Route, 0, len(p.byName))
+	for _, route := range p.byName {
+		routes = append(routes, *route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].Name < routes[j].Name
+	})
+	return routes
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	hostRoute := p.byHost[requestHost(r)]
+	p.mu.RUnlock()
+
+	if hostRoute != nil {
+		hostRoute.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if r.URL.Path == "/" || r.URL.Path == "/apps" || r.URL.Path == AppsPrefix {
+		p.serveIndex(w)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, AppsPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+
+	name, rest, found := strings.Cut(strings.TrimPrefix(r.URL.Path, AppsPrefix), "/")
+	if !found {
+		// Relative links in the app only resolve with the trailing slash
+		target := AppsPrefix + name + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+		return
+	}
+
+	p.mu.RLock()
+	route := p.byName[strings.ToLower(name)]
+	p.mu.RUnlock()
+
+	if route == nil {
+		http.Error(w, fmt.Sprintf("No running app named %q", name), http.StatusNotFound)
+		return
+	}
+
+	r2 := r.Clone(r.Context())
+	r2.URL.Path = "/" + rest
+	r2.URL.RawPath = ""
+	route.handler.ServeHTTP(w, r2)
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><title>BlueNode Apps</title></head>
+<body>
+<h1>Apps</h1>
+{{if .}}<ul>
+{{range .}}<li><a href="{{.Prefix}}">{{.Name}}</a> ({{.Container}})</li>
+{{end}}</ul>{{else}}<p>No running containers have a web interface label.</p>{{end}}
+</body>
+</html>
+`))
+
+func (p *Proxy) serveIndex(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, p.Routes()); err != nil {
+		log.Printf("Failed to render web proxy index: %v", err)
+	}
+}
+
+func (p *Proxy) buildRoute(c docker.Container) (*Route, error) {
+	port, err := strconv.Atoi(c.Labels[LabelPort])
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid %s label %q", LabelPort, c.Labels[LabelPort])
+	}
+
+	address, err := targetAddress(c, uint16(port))
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(c.Labels[LabelName])
+	if name == "" {
+		name = strings.ToLower(containerName(c))
+	}
+	if name == "" || strings.ContainsAny(name, "/?#") {
+		return nil, fmt.Errorf("invalid app name %q", name)
+	}
+
+	basePath := "/" + strings.Trim(c.Labels[LabelPath], "/")
+
+	route := &Route{
+		Name:        name,
+		Container:   containerName(c),
+		ContainerID: c.ID,
+		Target:      "http://" + address,
+		Path:        basePath,
+		Prefix:      AppsPrefix + name + "/",
+	}
+
+	for _, host := range strings.Split(c.Labels[LabelHost], ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			route.Hosts = append(route.Hosts, host)
+		}
+	}
+	if p.domain != "" {
+		route.Hosts = append(route.Hosts, name+"."+p.domain)
+	}
+
+	target, err := url.Parse(route.Target)
+	if err != nil {
+		return nil, err
+	}
+	route.handler = newReverseProxy(route, target)
+
+	return route, nil
+}
+
+func newReverseProxy(route *Route, target *url.URL) http.Handler {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.URL.Path = joinPath(route.Path, pr.In.URL.Path)
+			pr.Out.URL.RawPath = ""
+			pr.SetXForwarded()
+
+			if strings.HasPrefix(pr.In.RequestURI, route.Prefix) {
+				pr.Out.Header.Set("X-Forwarded-Prefix", strings.TrimSuffix(route.Prefix, "/"))
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// Keep redirects issued by the app under its /apps/<name>/ prefix
+			location := resp.Header.Get("Location")
+			if location == "" || !strings.HasPrefix(location, "/") || strings.HasPrefix(location, "//") {
+				return nil
+			}
+			if resp.Request == nil || resp.Request.Header.Get("X-Forwarded-Prefix") == "" {
+				return nil
+			}
+
+			rest := location
+			if route.Path != "/" {
+				if !strings.HasPrefix(location, route.Path) {
+					return nil
+				}
+				rest = strings.TrimPrefix(location, route.Path)
+			}
+			resp.Header.Set("Location", joinPath(route.Prefix, rest))
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("Web proxy error for %s: %v", route.Name, err)
+			http.Error(w, fmt.Sprintf("App %s is not responding", route.Name), http.StatusBadGateway)
+		},
+	}
+}
+
+// targetAddress returns the port published on the host. Ports of containers
+// on the host network are reachable as they are. Other unpublished ports are
+// only reached through the container's IP address when the container opts in
+// with the bluenode.web.direct label, so the proxy does not expose ports the
+// user kept private.
+func targetAddress(c docker.Container, port uint16) (string, error) {
+	for _, p := range c.Ports {
+		if p.PrivatePort != port || p.PublicPort == 0 || p.Type != "tcp" {
+			continue
+		}
+		ip := p.IP
+		if ip == "" || ip == "0.0.0.0" || ip == "::" {
+			ip = "127.0.0.1"
+		}
+		return net.JoinHostPort(ip, strconv.Itoa(int(p.PublicPort))), nil
+	}
+
+	direct := c.Labels[LabelDirect] == "true"
+	if c.NetworkSettings != nil {
+		names := make([]string, 0, len(c.NetworkSettings.Networks))
+		for name := range c.NetworkSettings.Networks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if name == "host" {
+				return net.JoinHostPort("127.0.0.1", strconv.Itoa(int(port))), nil
+			}
+			if ip := c.NetworkSettings.Networks[name].IPAddress; ip != "" && direct {
+				return net.JoinHostPort(ip, strconv.Itoa(int(port))), nil
+			}
+		}
+	}
+
+	if !direct {
+		return "", fmt.Errorf("port %d is not published, set %s=true to reach it on the container network", port, LabelDirect)
+	}
+	return "", fmt.Errorf("port %d is neither published nor reachable on a container network", port)
+}
+
+func joinPath(base, rest string) string {
+	joined := path.Join(base, rest)
+	if strings.HasSuffix(rest, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined
+}
+
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+func containerName(c docker.Container) string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
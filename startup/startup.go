@@ -0,0 +1,524 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Dependency-aware container start and stop ordering
+
+package startup
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/docker"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTimeout = 120 * time.Second
+	pollInterval   = 2 * time.Second
+	stopTimeout    = 10
+)
+
+// Result states reported for each container.
+const (
+	StatusStarted        = "started"
+	StatusAlreadyRunning = "already_running"
+	StatusStopped        = "stopped"
+	StatusNotRunning     = "not_running"
+	StatusMissing        = "missing"
+	StatusSkipped        = "skipped"
+	StatusFailed         = "failed"
+)
+
+// ErrAutoRestart is returned for a container that Docker would start by
+// itself when the daemon comes up.
+var ErrAutoRestart = errors.New("restart policy conflicts with the startup order")
+
+// autoRestartPolicies are the restart policies under which dockerd starts a
+// container on its own at daemon start, ahead of the startup order.
+var autoRestartPolicies = map[string]bool{
+	"always":         true,
+	"unless-stopped": true,
+}
+
+// Stage is a set of containers that can be started together because
+// everything they depend on is in an earlier stage.
+type Stage struct {
+	Containers []string `json:"containers"`
+}
+
+type Result struct {
+	ContainerName string `json:"container_name"`
+	Stage         int    `json:"stage"`
+	Status        string `json:"status"`
+	Error         string `json:"error,omitempty"`
+	DurationMs    int64  `json:"duration_ms"`
+}
+
+// Sequencer starts containers in dependency order and stops them in reverse.
+// Ordering comes from two sources: explicit dependencies between containers,
+// and groups, where every container in a lower group starts before any
+// container in a higher one.
+type Sequencer struct {
+	client *docker.Client
+	store  *database.StartupStore
+
+	// mu serialises start and stop runs so on-demand requests cannot
+	// interleave with the boot sequence.
+	mu sync.Mutex
+}
+
+func New(client *docker.Client, store *database.StartupStore) *Sequencer {
+	return &Sequencer{
+		client: client,
+		store:  store,
+	}
+}
+
+// CheckRestartPolicy makes sure Docker leaves starting the container to the
+// sequencer. A restart policy of always or unless-stopped is an error unless
+// disable is set, in which case the policy is changed to no and a warning
+// describing the change is returned. Containers that do not exist yet are
+// not checked.
+func (s *Sequencer) CheckRestartPolicy(ctx context.Context, name string, disable bool) (string, error) {
+	details, err := s.client.InspectContainer(ctx, name)
+	if errors.Is(err, docker.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	policy := details.HostConfig.RestartPolicy.Name
+	if !autoRestartPolicies[policy] {
+		return "", nil
+	}
+	if !disable {
+		return "", fmt.Errorf("%w: %s has restart policy %s, which Docker applies before the startup order runs; use no or on-failure", ErrAutoRestart, name, policy)
+	}
+
+	if _, err := s.client.UpdateContainer(ctx, details.ID, docker.UpdateConfig{
+		RestartPolicy: &docker.RestartPolicy{Name: "no"},
+	}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("restart policy of %s changed from %s to no", name, policy), nil
+}
+
+// Plan returns the start stages for the enabled entries.
+func (s *Sequencer) Plan() ([]Stage, error) {
+	entries, err := s.enabledEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	stages, err := BuildPlan(entries)
+	if err != nil {
+		return nil, err
+	}
+	return stages, nil
+}
+
+// Boot waits for the Docker daemon, which may still be coming up after a
+// reboot, and then runs StartAll once.
+func (s *Sequencer) Boot(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for s.client.Ping(ctx) != nil {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	results, err := s.StartAll(ctx)
+	if err != nil {
+		log.Printf("Startup sequence failed: %v", err)
+		return
+	}
+	log.Printf("Startup sequence finished for %d containers", len(results))
+}
+
+// StartAll starts every enabled container stage by stage, waiting for each
+// stage to be running (and healthy, where requested) before moving on. A
+// container whose dependency failed is skipped rather than started.
+func (s *Sequencer) StartAll(ctx context.Context) ([]Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.enabledEntries()
+	if err != nil {
+		return nil, err
+	}
+	stages, err := BuildPlan(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]database.StartupEntry)
+	for _, e := range entries {
+		byName[e.ContainerName] = e
+	}
+
+	ids, err := s.containerIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make(map[string]bool)
+	var results []Result
+
+	for i, stage := range stages {
+		stageResults := make([]Result, len(stage.Containers))
+
+		var wg sync.WaitGroup
+		for j, name := range stage.Containers {
+			entry, ok := byName[name]
+			if !ok {
+				// Dependency without an entry of its own
+				entry = database.StartupEntry{ContainerName: name, WaitHealthy: true}
+			}
+
+			if dep := failedDependency(entry, failed); dep != "" {
+				stageResults[j] = Result{
+					ContainerName: name,
+					Stage:         i,
+					Status:        StatusSkipped,
+					Error:         fmt.Sprintf("dependency %s did not start", dep),
+				}
+				continue
+			}
+
+			wg.Add(1)
+			go func(j int, entry database.StartupEntry) {
+				defer wg.Done()
+				stageResults[j] = s.start(ctx, entry, ids[entry.ContainerName])
+				stageResults[j].Stage = i
+			}(j, entry)
+		}
+		wg.Wait()
+
+		for _, result := range stageResults {
+			if result.Status == StatusFailed || result.Status == StatusMissing || result.Status == StatusSkipped {
+				failed[result.ContainerName] = true
+			}
+			log.Printf("Startup stage %d: %s %s", i, result.ContainerName, result.Status)
+		}
+		results = append(results, stageResults...)
+
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+	}
+
+	return results, nil
+}
+
+// StopAll stops every enabled container in the reverse of the start order,
+// one stage at a time.
+func (s *Sequencer) StopAll(ctx context.Context) ([]Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.enabledEntries()
+	if err != nil {
+		return nil, err
+	}
+	stages, err := BuildPlan(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := s.containerIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for i := len(stages) - 1; i >= 0; i-- {
+		stageResults := make([]Result, len(stages[i].Containers))
+
+		var wg sync.WaitGroup
+		for j, name := range stages[i].Containers {
+			wg.Add(1)
+			go func(j int, name string) {
+				defer wg.Done()
+				stageResults[j] = s.stop(ctx, name, ids[name])
+				stageResults[j].Stage = i
+			}(j, name)
+		}
+		wg.Wait()
+
+		for _, result := range stageResults {
+			log.Printf("Shutdown stage %d: %s %s", i, result.ContainerName, result.Status)
+		}
+		results = append(results, stageResults...)
+
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+	}
+
+	return results, nil
+}
+
+func (s *Sequencer) start(ctx context.Context, entry database.StartupEntry, id string) (result Result) {
+	started := time.Now()
+	result = Result{ContainerName: entry.ContainerName}
+	defer func() {
+		result.DurationMs = time.Since(started).Milliseconds()
+	}()
+
+	if id == "" {
+		result.Status = StatusMissing
+		result.Error = "container not found"
+		return result
+	}
+
+	details, err := s.client.InspectContainer(ctx, id)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	if policy := details.HostConfig.RestartPolicy.Name; autoRestartPolicies[policy] {
+		log.Printf("Startup order for %s may not hold: Docker starts it by itself (restart policy %s)", entry.ContainerName, policy)
+	}
+
+	result.Status = StatusAlreadyRunning
+	if !details.State.Running {
+		if err := s.client.StartContainer(ctx, id); err != nil {
+			result.Status = StatusFailed
+			result.Error = err.Error()
+			return result
+		}
+		result.Status = StatusStarted
+	}
+
+	timeout := DefaultTimeout
+	if entry.TimeoutSeconds > 0 {
+		timeout = time.Duration(entry.TimeoutSeconds) * time.Second
+	}
+	if err := s.waitReady(ctx, id, entry.WaitHealthy, timeout); err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// waitReady polls the container until it is running and, when waitHealthy is
+// set and the image defines a healthcheck, reports healthy.
+func (s *Sequencer) waitReady(ctx context.Context, id string, waitHealthy bool, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		details, err := s.client.InspectContainer(ctx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("not ready after %s", timeout)
+			}
+			return err
+		}
+
+		st := details.State
+		if !st.Running && !st.Restarting && st.Status != "created" {
+			return fmt.Errorf("container %s with exit code %d", st.Status, st.ExitCode)
+		}
+		if st.Running && (!waitHealthy || st.Health == nil || st.Health.Status == "healthy") {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if st.Health != nil {
+				return fmt.Errorf("not healthy after %s (health: %s)", timeout, st.Health.Status)
+			}
+			return fmt.Errorf("not running after %s", timeout)
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (s *Sequencer) stop(ctx context.Context, name, id string) (result Result) {
+	started := time.Now()
+	result = Result{ContainerName: name, Status: StatusStopped}
+	defer func() {
+		result.DurationMs = time.Since(started).Milliseconds()
+	}()
+
+	if id == "" {
+		result.Status = StatusMissing
+		return result
+	}
+
+	details, err := s.client.InspectContainer(ctx, id)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	if !details.State.Running {
+		result.Status = StatusNotRunning
+		return result
+	}
+
+	if err := s.client.StopContainer(ctx, id, stopTimeout); err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (s *Sequencer) enabledEntries() ([]database.StartupEntry, error) {
+	entries, err := s.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := entries[:0]
+	for _, e := range entries {
+		if e.Enabled {
+			enabled = append(enabled, e)
+		}
+	}
+	return enabled, nil
+}
+
+// containerIDs maps container names to IDs for every container, running or not.
+func (s *Sequencer) containerIDs(ctx context.Context) (map[string]string, error) {
+	containers, err := s.client.ListContainers(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string)
+	for _, c := range containers {
+		for _, name := range c.Names {
+			ids[strings.TrimPrefix(name, "/")] = c.ID
+		}
+	}
+	return ids, nil
+}
+
+func failedDependency(entry database.StartupEntry, failed map[string]bool) string {
+	for _, dep := range entry.DependsOn {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
+
+// BuildPlan orders entries into stages with Kahn's algorithm. Dependencies
+// that have no entry of their own are included in the dependent's group.
+// A dependency cycle is reported as an error naming the containers involved.
+func BuildPlan(entries []database.StartupEntry) ([]Stage, error) {
+	groups := make(map[string]int)
+	deps := make(map[string][]string)
+	for _, e := range entries {
+		groups[e.ContainerName] = e.Group
+		deps[e.ContainerName] = e.DependsOn
+	}
+	for _, e := range entries {
+		for _, dep := range e.DependsOn {
+			if _, ok := groups[dep]; !ok {
+				groups[dep] = e.Group
+			}
+		}
+	}
+
+	// Edges run from a container to the containers that must wait for it
+	edges := make(map[string][]string)
+	indegree := make(map[string]int)
+	addEdge := func(from, to string) {
+		edges[from] = append(edges[from], to)
+		indegree[to]++
+	}
+
+	for name := range groups {
+		indegree[name] = 0
+	}
+	for name := range groups {
+		for _, dep := range deps[name] {
+			if dep == name {
+				return nil, fmt.Errorf("container %s depends on itself", name)
+			}
+			addEdge(dep, name)
+		}
+	}
+
+	// Each group waits for the group directly below it
+	var levels []int
+	byGroup := make(map[int][]string)
+	for name, group := range groups {
+		if _, ok := byGroup[group]; !ok {
+			levels = append(levels, group)
+		}
+		byGroup[group] = append(byGroup[group], name)
+	}
+	sort.Ints(levels)
+	for i := 1; i < len(levels); i++ {
+		for _, from := range byGroup[levels[i-1]] {
+			for _, to := range byGroup[levels[i]] {
+				addEdge(from, to)
+			}
+		}
+	}
+
+	var stages []Stage
+	var ready []string
+	for name, n := range indegree {
+		if n == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	placed := 0
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		stages = append(stages, Stage{Containers: ready})
+		placed += len(ready)
+
+		var next []string
+		for _, name := range ready {
+			for _, to := range edges[name] {
+				indegree[to]--
+				if indegree[to] == 0 {
+					next = append(next, to)
+				}
+			}
+		}
+		ready = next
+	}
+
+	if placed != len(indegree) {
+		var cycle []string
+		for name, n := range indegree {
+			if n > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("cannot order containers, check groups and dependencies for a cycle: %s", strings.Join(cycle, ", "))
+	}
+
+	return stages, nil
+}
+
+// SystemStopping reports whether systemd is shutting the machine down, as
+// opposed to only stopping the helper.
+func SystemStopping(ctx context.Context) bool {
+	// is-system-running exits non-zero for every state but "running", so
+	// only the output is checked
+	out, _ := exec.CommandContext(ctx, "systemctl", "is-system-running").Output()
+	return strings.TrimSpace(string(out)) == "stopping"
+}
@@ -0,0 +1,328 @@
+// Author: retrozenith <80767544+retrozenith@users.noreply.github.com>
+// Description: Container health supervisor applying auto-heal policies
+
+package supervisor
+
+import (
+	"bluenode-helper/database"
+	"bluenode-helper/docker"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultInterval = 30 * time.Second
+	restartTimeout  = 10
+	retryInterval   = 5 * time.Second
+)
+
+// Supervisor polls container state and health through the Docker client and
+// restarts containers according to the policies stored in the config database.
+// Docker's own restart policy ignores healthcheck failures, which is the gap
+// this fills.
+type Supervisor struct {
+	client   *docker.Client
+	store    *database.HealthStore
+	interval time.Duration
+
+	mu     sync.Mutex
+	states map[string]*containerState
+	// stops holds when each container, by ID, was last stopped or killed
+	// through the Docker API, by a user or by the helper. watchingSince is
+	// when the supervisor started following these events.
+	stops         map[string]time.Time
+	watchingSince time.Time
+}
+
+// containerState is the in-memory bookkeeping for one supervised container,
+// keyed by name so that it survives the container being recreated.
+type containerState struct {
+	restarts    []time.Time
+	backoff     time.Duration
+	nextAttempt time.Time
+	rateLimited bool
+}
+
+func New(client *docker.Client, store *database.HealthStore, interval time.Duration) *Supervisor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Supervisor{
+		client:   client,
+		store:    store,
+		interval: interval,
+		states:   make(map[string]*containerState),
+		stops:    make(map[string]time.Time),
+	}
+}
+
+// Run blocks until ctx is cancelled, checking all policies once per interval.
+func (s *Supervisor) Run(ctx context.Context) {
+	log.Printf("Health supervisor started (interval %s)", s.interval)
+
+	go s.watchStops(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Health supervisor stopped")
+			return
+		case <-ticker.C:
+			if err := s.CheckOnce(ctx); err != nil {
+				log.Printf("Health supervisor check failed: %v", err)
+			}
+		}
+	}
+}
+
+// CheckOnce evaluates every enabled policy against the current container state.
+func (s *Supervisor) CheckOnce(ctx context.Context) error {
+	policies, err := s.store.ListPolicies()
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+
+	containers, err := s.client.ListContainers(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]docker.Container)
+	for _, c := range containers {
+		for _, name := range c.Names {
+			byName[strings.TrimPrefix(name, "/")] = c
+		}
+	}
+
+	for _, policy := range policies {
+		if !policy.Enabled {
+			continue
+		}
+
+		container, ok := byName[policy.ContainerName]
+		if !ok {
+			continue
+		}
+
+		s.evaluate(ctx, policy, container)
+	}
+
+	return nil
+}
+
+// watchStops records stop and kill events until ctx is cancelled. A crash
+// only produces a die event, so these tell intentional stops apart.
+func (s *Supervisor) watchStops(ctx context.Context) {
+	filters := docker.Filters{}
+	filters.Add("type", "container")
+	filters.Add("event", "stop", "kill", "destroy")
+
+	for {
+		s.mu.Lock()
+		if s.watchingSince.IsZero() {
+			s.watchingSince = time.Now()
+		}
+		s.mu.Unlock()
+
+		err := s.client.Events(ctx, filters, func(event docker.Event) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			if event.Action == "destroy" {
+				delete(s.stops, event.Actor.ID)
+				return
+			}
+			s.stops[event.Actor.ID] = time.Unix(0, event.TimeNano)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Health supervisor lost the Docker event stream: %v", err)
+		}
+
+		// Stops during the gap are not seen
+		s.mu.Lock()
+		s.watchingSince = time.Time{}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// stoppedIntentionally reports whether an exited container was stopped by
+// a user or the helper rather than crashing. Containers that exited while
+// the supervisor was not watching count as stopped when they received
+// SIGTERM or SIGKILL (exit code 143 or 137) without running out of memory.
+func (s *Supervisor) stoppedIntentionally(details *docker.ContainerJSON) bool {
+	st := details.State
+
+	s.mu.Lock()
+	stoppedAt, stopped := s.stops[details.ID]
+	watchingSince := s.watchingSince
+	s.mu.Unlock()
+
+	startedAt, err := time.Parse(time.RFC3339Nano, st.StartedAt)
+	if err != nil {
+		return false
+	}
+	if stopped && !stoppedAt.Before(startedAt) {
+		return true
+	}
+
+	finishedAt, err := time.Parse(time.RFC3339Nano, st.FinishedAt)
+	if err != nil {
+		return false
+	}
+	if !watchingSince.IsZero() && finishedAt.After(watchingSince) {
+		return false
+	}
+	return !st.OOMKilled && (st.ExitCode == 137 || st.ExitCode == 143)
+}
+
+func (s *Supervisor) evaluate(ctx context.Context, policy database.HealthPolicy, container docker.Container) {
+	details, err := s.client.InspectContainer(ctx, container.ID)
+	if err != nil {
+		log.Printf("Health supervisor failed to inspect %s: %v", policy.ContainerName, err)
+		return
+	}
+
+	reason, start := s.needsAction(policy, details)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[policy.ContainerName]
+	if !ok {
+		state = &containerState{}
+		s.states[policy.ContainerName] = state
+	}
+
+	now := time.Now()
+	state.restarts = pruneBefore(state.restarts, now.Add(-time.Hour))
+	if len(state.restarts) == 0 {
+		state.rateLimited = false
+	}
+
+	if reason == "" {
+		// A healthy, running container resets the backoff so the next
+		// failure is handled promptly again.
+		if details.State.Running {
+			state.backoff = 0
+			state.nextAttempt = time.Time{}
+		}
+		return
+	}
+
+	if now.Before(state.nextAttempt) {
+		return
+	}
+
+	if policy.MaxRestartsPerHour > 0 && len(state.restarts) >= policy.MaxRestartsPerHour {
+		if !state.rateLimited {
+			state.rateLimited = true
+			s.record(policy.ContainerName, container.ID, "rate_limited",
+				fmt.Sprintf("%s; %d restarts in the last hour, limit is %d", reason, len(state.restarts), policy.MaxRestartsPerHour), nil)
+		}
+		return
+	}
+
+	action := "restart"
+	if start {
+		action = "start"
+		err = s.client.StartContainer(ctx, container.ID)
+	} else {
+		err = s.client.RestartContainer(ctx, container.ID, restartTimeout)
+	}
+
+	s.record(policy.ContainerName, container.ID, action, reason, err)
+	log.Printf("Health supervisor: %s %s (%s)", action, policy.ContainerName, reason)
+
+	state.restarts = append(state.restarts, now)
+	state.backoff = nextBackoff(state.backoff, policy)
+	state.nextAttempt = now.Add(state.backoff)
+}
+
+// needsAction returns a human-readable reason when the container must be
+// acted upon, and whether it needs starting rather than restarting.
+func (s *Supervisor) needsAction(policy database.HealthPolicy, details *docker.ContainerJSON) (string, bool) {
+	st := details.State
+
+	if st.Running && !st.Paused && st.Health != nil && st.Health.Status == "unhealthy" {
+		threshold := policy.UnhealthyThreshold
+		if threshold <= 0 {
+			threshold = 1
+		}
+		if st.Health.FailingStreak >= threshold {
+			return fmt.Sprintf("unhealthy after %d consecutive failed checks", st.Health.FailingStreak), false
+		}
+	}
+
+	if policy.RestartOnExit && st.Status == "exited" && st.ExitCode != 0 && !s.stoppedIntentionally(details) {
+		if st.OOMKilled {
+			return fmt.Sprintf("exited with code %d (OOM killed)", st.ExitCode), true
+		}
+		return fmt.Sprintf("exited with code %d", st.ExitCode), true
+	}
+
+	return "", false
+}
+
+func (s *Supervisor) record(name, containerID, action, reason string, err error) {
+	entry := &database.HealthAction{
+		ContainerName: name,
+		ContainerID:   containerID,
+		Action:        action,
+		Reason:        reason,
+		Success:       err == nil,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	if err := s.store.RecordAction(entry); err != nil {
+		log.Printf("Health supervisor failed to record action: %v", err)
+	}
+}
+
+func nextBackoff(current time.Duration, policy database.HealthPolicy) time.Duration {
+	initial := time.Duration(policy.BackoffSeconds) * time.Second
+	max := time.Duration(policy.MaxBackoffSeconds) * time.Second
+
+	next := initial
+	if current > 0 && current < math.MaxInt64/2 {
+		next = current * 2
+	} else if current > 0 {
+		next = current
+	}
+	if max > 0 && next > max {
+		next = max
+	}
+	return next
+}
+
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	kept := times[:0]
+	for _, t := range times {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}